@@ -6,14 +6,18 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/joho/godotenv"
 
+	"drigo/pkg/blob"
 	"drigo/pkg/bucket"
 	"drigo/pkg/discord"
 	"drigo/pkg/server"
 	"drigo/pkg/sqlite"
+	"drigo/pkg/units"
 )
 
 // Bot parameters
@@ -60,6 +64,11 @@ func init() {
 func main() {
 	flag.Parse()
 
+	if flag.Arg(0) == "migrate" {
+		runMigrate(flag.Args()[1:])
+		return
+	}
+
 	if botToken == nil || *botToken == "" {
 		log.Fatalf("Bot token flag is required")
 	}
@@ -73,36 +82,31 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	dbPath := cmp.Or(os.Getenv("SQLITE_PATH"), filepath.Join("data", "sqlite.db"))
-	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
-		log.Fatalf("Failed to create database directory: %v", err)
+	dbPath := databasePath()
+	s3Client := newS3(ctx)
+	// bucket.Uploader is an interface, so only assign a working client.
+	var uploader bucket.Uploader
+	if s3Client != nil {
+		uploader = s3Client
 	}
+	store := newBlobStore(dbPath, s3Client)
 
-	sqliteDB, err := sqlite.Connect(dbPath, ctx)
+	maxUploadSize, err := units.ParseSize(cmp.Or(os.Getenv("MAX_UPLOAD_SIZE"), "4GiB"))
 	if err != nil {
-		log.Fatalf("Failed to create sqlite database: %v", err)
+		log.Fatalf("Invalid MAX_UPLOAD_SIZE: %v", err)
+	}
+	maxFileSize, err := units.ParseSize(cmp.Or(os.Getenv("MAX_FILE_SIZE"), "2GiB"))
+	if err != nil {
+		log.Fatalf("Invalid MAX_FILE_SIZE: %v", err)
+	}
+	trashRetention, err := time.ParseDuration(cmp.Or(os.Getenv("TRASH_RETENTION"), "720h"))
+	if err != nil {
+		log.Fatalf("Invalid TRASH_RETENTION: %v", err)
 	}
 
-	// Optional S3 configuration
-	accessKey := os.Getenv("ACCESS_KEY")
-	secretKey := os.Getenv("SECRET_KEY")
-	s3Bucket := os.Getenv("S3_BUCKET")
-	s3Region := os.Getenv("S3_REGION")
-	s3Endpoint := os.Getenv("S3_ENDPOINT")
-	s3Prefix := os.Getenv("S3_PREFIX")
-
-	var uploader bucket.Uploader
-	if accessKey != "" && secretKey != "" && s3Bucket != "" {
-		// Construct S3 uploader; errors are non-fatal, we'll log and continue without fallback uploads
-		if s3Region == "" {
-			s3Region = "us-east-1"
-		}
-		s3u, err := bucket.NewS3(ctx, accessKey, secretKey, s3Region, s3Bucket, s3Endpoint, s3Prefix)
-		if err != nil {
-			log.Errorf("Failed to initialize S3 uploader: %v", err)
-		} else {
-			uploader = s3u
-		}
+	sqliteDB, err := sqlite.Connect(dbPath, ctx, store)
+	if err != nil {
+		log.Fatalf("Failed to create sqlite database: %v", err)
 	}
 
 	bot, err := discord.New(&discord.Config{
@@ -127,9 +131,103 @@ func main() {
 		DB:           sqliteDB,
 		Bot:          bot,
 		Bucket:       uploader,
+		Blobs:        store,
+
+		MaxUploadSize:  maxUploadSize,
+		MaxFileSize:    maxFileSize,
+		UploadDir:      cmp.Or(os.Getenv("UPLOAD_PATH"), filepath.Join(filepath.Dir(dbPath), "uploads")),
+		PublicURL:      strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/"),
+		TrashRetention: trashRetention,
 	})
 
 	if err := srv.Run(); err != nil {
 		log.Fatalf("Shutdown failed: %v", err)
 	}
 }
+
+// databasePath returns the SQLite path and makes sure its directory exists.
+func databasePath() string {
+	dbPath := cmp.Or(os.Getenv("SQLITE_PATH"), filepath.Join("data", "sqlite.db"))
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		log.Fatalf("Failed to create database directory: %v", err)
+	}
+	return dbPath
+}
+
+// newS3 returns the optional S3 client, or nil when it isn't configured.
+func newS3(ctx context.Context) *bucket.S3 {
+	accessKey := os.Getenv("ACCESS_KEY")
+	secretKey := os.Getenv("SECRET_KEY")
+	s3Bucket := os.Getenv("S3_BUCKET")
+	s3Region := os.Getenv("S3_REGION")
+	s3Endpoint := os.Getenv("S3_ENDPOINT")
+	s3Prefix := os.Getenv("S3_PREFIX")
+
+	if accessKey == "" || secretKey == "" || s3Bucket == "" {
+		return nil
+	}
+	// Construct S3 uploader; errors are non-fatal, we'll log and continue without fallback uploads
+	if s3Region == "" {
+		s3Region = "us-east-1"
+	}
+	s3u, err := bucket.NewS3(ctx, accessKey, secretKey, s3Region, s3Bucket, s3Endpoint, s3Prefix)
+	if err != nil {
+		log.Errorf("Failed to initialize S3 uploader: %v", err)
+		return nil
+	}
+	return s3u
+}
+
+// newBlobStore opens the configured media store. Media payloads live in the
+// blob store; SQLite only keeps their keys.
+func newBlobStore(dbPath string, s3Client *bucket.S3) blob.Store {
+	switch backend := cmp.Or(os.Getenv("BLOB_STORE"), "local"); backend {
+	case "local":
+		blobPath := cmp.Or(os.Getenv("BLOB_PATH"), filepath.Join(filepath.Dir(dbPath), "blobs"))
+		local, err := blob.NewLocal(blobPath)
+		if err != nil {
+			log.Fatalf("Failed to create blob store: %v", err)
+		}
+		return local
+	case "s3":
+		if s3Client == nil {
+			log.Fatalf("BLOB_STORE=s3 requires a working S3 configuration")
+		}
+		return blob.NewS3(s3Client)
+	default:
+		log.Fatalf("Unknown BLOB_STORE %q (expected local or s3)", backend)
+		return nil
+	}
+}
+
+// runMigrate implements the "migrate" subcommand, which applies pending schema
+// migrations and exits without starting the bot or HTTP server.
+func runMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "List pending migrations without applying them")
+	_ = fs.Parse(args)
+
+	ctx := context.Background()
+	dbPath := databasePath()
+
+	var store blob.Store
+	if !*dryRun {
+		store = newBlobStore(dbPath, newS3(ctx))
+	}
+
+	migrations, err := sqlite.Migrate(dbPath, ctx, store, *dryRun)
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
+	switch {
+	case len(migrations) == 0:
+		log.Info("Database is up to date", "path", dbPath)
+	case *dryRun:
+		for _, m := range migrations {
+			log.Info("Pending migration", "version", m.Version, "name", m.Name)
+		}
+	default:
+		log.Info("Applied migrations", "count", len(migrations), "path", dbPath)
+	}
+}
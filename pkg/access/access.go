@@ -0,0 +1,92 @@
+// Package access decides who may view a post. The web server and the Discord
+// bot both evaluate it, so the gallery and the bot agree.
+package access
+
+import (
+	"slices"
+	"time"
+
+	"drigo/pkg/types"
+)
+
+// Tiers ranks Discord roles for access checks.
+type Tiers struct {
+	byRole map[string]int
+	byID   map[uint]int
+}
+
+// NewTiers indexes tiers by role and by ID.
+func NewTiers(tiers []types.Tier) Tiers {
+	t := Tiers{
+		byRole: make(map[string]int, len(tiers)),
+		byID:   make(map[uint]int, len(tiers)),
+	}
+	for _, tier := range tiers {
+		t.byRole[tier.RoleID] = tier.Rank
+		t.byID[tier.ID] = tier.Rank
+	}
+	return t
+}
+
+// Rank returns the highest rank among roleIDs, or 0 if none is a tier.
+func (t Tiers) Rank(roleIDs []string) int {
+	var rank int
+	for _, id := range roleIDs {
+		rank = max(rank, t.byRole[id])
+	}
+	return rank
+}
+
+// Required returns the lowest rank that opens p, either its minimum tier or
+// a tier role it allows, or 0 if no tier does.
+func (t Tiers) Required(p *types.Post) int {
+	var need int
+	lower := func(rank int) {
+		if rank > 0 && (need == 0 || rank < need) {
+			need = rank
+		}
+	}
+	if p.MinTierID != nil {
+		lower(t.byID[*p.MinTierID])
+	}
+	for _, ar := range p.AllowedRoles {
+		lower(t.byRole[ar.RoleID])
+	}
+	return need
+}
+
+// Gated reports whether p is restricted to roles or a tier right now. A post
+// whose early-access window has closed is open to everyone.
+func Gated(p *types.Post) bool {
+	return (len(p.AllowedRoles) > 0 || p.MinTierID != nil) && !Public(p, time.Now())
+}
+
+// Public reports whether the early-access window of p has closed at now.
+func Public(p *types.Post, now time.Time) bool {
+	return p.PublicAfter != nil && !now.Before(*p.PublicAfter)
+}
+
+// CanView reports whether a member holding roleIDs may view p: it is open to
+// everyone, they hold one of its roles, or their tier reaches the one it
+// requires. Admin rights and publication state are left to the caller.
+func (t Tiers) CanView(roleIDs []string, p *types.Post) bool {
+	if !Gated(p) {
+		return true
+	}
+	for _, ar := range p.AllowedRoles {
+		if slices.Contains(roleIDs, ar.RoleID) {
+			return true
+		}
+	}
+	need := t.Required(p)
+	return need > 0 && t.Rank(roleIDs) >= need
+}
+
+// Grant applies a user's active grant on top of a role decision: a deny always
+// wins, an allow opens the post, and no grant leaves the decision alone.
+func Grant(g *types.PostGrant, viewable bool) bool {
+	if g == nil {
+		return viewable
+	}
+	return g.Effect == types.GrantAllow
+}
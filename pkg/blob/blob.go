@@ -0,0 +1,50 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/segmentio/ksuid"
+)
+
+// ErrNotFound is returned when a key does not exist in the store.
+var ErrNotFound = errors.New("blob not found")
+
+// Info describes a stored object.
+type Info struct {
+	Key      string
+	Size     int64
+	Checksum string // hex-encoded SHA-256 of the payload; may be empty from Stat
+	ModTime  time.Time
+}
+
+// Store keeps media payloads outside the database. Keys are slash-separated
+// paths such as "media/2bX..."; implementations decide how they map to disk
+// or object storage.
+type Store interface {
+	// Put writes r under key, replacing any existing object, and reports the
+	// size and SHA-256 checksum of what was written.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (Info, error)
+	// Get reads the whole object into memory.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Stat returns metadata without reading the payload.
+	Stat(ctx context.Context, key string) (Info, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// Stream opens a reader over length bytes starting at offset.
+	// A negative length reads until the end of the object.
+	Stream(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// NewKey returns a fresh unique key under prefix.
+func NewKey(prefix string) string {
+	return prefix + "/" + ksuid.New().String()
+}
+
+// readCloser pairs a limited reader with the closer of the underlying source.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,73 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ReadSeeker adapts a stored object to io.ReadSeeker so it can be served with
+// http.ServeContent. Seeking is free; the underlying stream is only opened
+// (at the current offset) on the next Read.
+type ReadSeeker struct {
+	ctx    context.Context
+	store  Store
+	key    string
+	size   int64
+	offset int64
+	rc     io.ReadCloser
+}
+
+// NewReadSeeker returns a ReadSeeker over key, whose total size must be known.
+func NewReadSeeker(ctx context.Context, store Store, key string, size int64) *ReadSeeker {
+	return &ReadSeeker{ctx: ctx, store: store, key: key, size: size}
+}
+
+func (r *ReadSeeker) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.rc == nil {
+		rc, err := r.store.Stream(r.ctx, r.key, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.rc = rc
+	}
+	n, err := r.rc.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.offset + offset
+	case io.SeekEnd:
+		abs = r.size + offset
+	default:
+		return 0, errors.New("blob: invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("blob: negative position")
+	}
+	if abs != r.offset && r.rc != nil {
+		r.rc.Close()
+		r.rc = nil
+	}
+	r.offset = abs
+	return abs, nil
+}
+
+// Close releases the underlying stream, if one is open.
+func (r *ReadSeeker) Close() error {
+	if r.rc == nil {
+		return nil
+	}
+	err := r.rc.Close()
+	r.rc = nil
+	return err
+}
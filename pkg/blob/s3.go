@@ -0,0 +1,98 @@
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"drigo/pkg/bucket"
+)
+
+// checksumMetadata is the object metadata key holding the payload SHA-256.
+const checksumMetadata = "sha256"
+
+// S3 stores objects in an S3 (or S3-compatible) bucket.
+type S3 struct {
+	bucket *bucket.S3
+}
+
+// NewS3 wraps an already configured bucket.
+func NewS3(b *bucket.S3) *S3 {
+	return &S3{bucket: b}
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, contentType string) (Info, error) {
+	// S3 needs a known content length, so spool to disk while hashing.
+	tmp, err := os.CreateTemp("", "drigo_blob_*")
+	if err != nil {
+		return Info{}, fmt.Errorf("create spool file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		return Info{}, fmt.Errorf("spool blob: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return Info{}, err
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if err := s.bucket.PutObject(ctx, key, tmp, size, contentType, map[string]string{checksumMetadata: sum}); err != nil {
+		return Info{}, err
+	}
+	return Info{Key: key, Size: size, Checksum: sum}, nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	rc, err := s.Stream(ctx, key, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (Info, error) {
+	obj, err := s.bucket.HeadObject(ctx, key)
+	if errors.Is(err, bucket.ErrNotFound) {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	return Info{
+		Key:      key,
+		Size:     obj.Size,
+		Checksum: obj.Metadata[checksumMetadata],
+		ModTime:  obj.LastModified,
+	}, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	return s.bucket.DeleteObject(ctx, key)
+}
+
+func (s *S3) Stream(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	var byteRange string
+	switch {
+	case length == 0:
+		return io.NopCloser(io.LimitReader(nil, 0)), nil
+	case length > 0:
+		byteRange = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	case offset > 0:
+		byteRange = fmt.Sprintf("bytes=%d-", offset)
+	}
+
+	rc, err := s.bucket.GetObject(ctx, key, byteRange)
+	if errors.Is(err, bucket.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return rc, err
+}
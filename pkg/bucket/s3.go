@@ -3,15 +3,29 @@ package bucket
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"strings"
+	"time"
 
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+// ErrNotFound is returned by object operations when the key does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo is the subset of object metadata returned by HeadObject.
+type ObjectInfo struct {
+	Size         int64
+	LastModified time.Time
+	Metadata     map[string]string
+}
+
 // S3 provides a simple Uploader backed by Amazon S3 or S3-compatible storage.
 type S3 struct {
 	client   *s3.Client
@@ -78,9 +92,7 @@ func (s *S3) Upload(ctx context.Context, key string, body []byte, contentType st
 	if key == "" {
 		return "", fmt.Errorf("key is required")
 	}
-	if s.prefix != "" {
-		key = strings.Trim(s.prefix+"/"+strings.TrimLeft(key, "/"), "/")
-	}
+	key = s.objectKey(key)
 
 	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:      &s.bucket,
@@ -106,3 +118,114 @@ func (s *S3) Upload(ctx context.Context, key string, body []byte, contentType st
 
 // Key returns the bucket name as a simple identifier.
 func (s *S3) Key() string { return strings.Trim(s.bucket+"/"+s.prefix, "/") }
+
+// objectKey applies the configured prefix to key.
+func (s *S3) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return strings.Trim(s.prefix+"/"+strings.TrimLeft(key, "/"), "/")
+}
+
+// PutObject streams body into the bucket under key. size must be the exact
+// length of body; metadata is stored as user-defined object metadata.
+func (s *S3) PutObject(ctx context.Context, key string, body io.Reader, size int64, contentType string, metadata map[string]string) error {
+	if s.client == nil {
+		return fmt.Errorf("s3 client is nil")
+	}
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	key = s.objectKey(key)
+
+	input := &s3.PutObjectInput{
+		Bucket:        &s.bucket,
+		Key:           &key,
+		Body:          body,
+		ContentLength: &size,
+		Metadata:      metadata,
+	}
+	if contentType != "" {
+		input.ContentType = &contentType
+	}
+	if _, err := s.client.PutObject(ctx, input); err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+	return nil
+}
+
+// GetObject opens the object under key. byteRange is an optional HTTP Range
+// header value such as "bytes=0-1023".
+func (s *S3) GetObject(ctx context.Context, key string, byteRange string) (io.ReadCloser, error) {
+	if s.client == nil {
+		return nil, fmt.Errorf("s3 client is nil")
+	}
+	key = s.objectKey(key)
+
+	input := &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	}
+	if byteRange != "" {
+		input.Range = &byteRange
+	}
+	out, err := s.client.GetObject(ctx, input)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("get object: %w", err)
+	}
+	return out.Body, nil
+}
+
+// HeadObject returns the size, modification time and metadata of key.
+func (s *S3) HeadObject(ctx context.Context, key string) (ObjectInfo, error) {
+	if s.client == nil {
+		return ObjectInfo{}, fmt.Errorf("s3 client is nil")
+	}
+	key = s.objectKey(key)
+
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return ObjectInfo{}, ErrNotFound
+		}
+		return ObjectInfo{}, fmt.Errorf("head object: %w", err)
+	}
+
+	info := ObjectInfo{Metadata: out.Metadata}
+	if out.ContentLength != nil {
+		info.Size = *out.ContentLength
+	}
+	if out.LastModified != nil {
+		info.LastModified = *out.LastModified
+	}
+	return info, nil
+}
+
+// DeleteObject removes key from the bucket. Missing keys are not an error.
+func (s *S3) DeleteObject(ctx context.Context, key string) error {
+	if s.client == nil {
+		return fmt.Errorf("s3 client is nil")
+	}
+	key = s.objectKey(key)
+
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	})
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("delete object: %w", err)
+	}
+	return nil
+}
+
+func isNotFound(err error) bool {
+	var noSuchKey *s3types.NoSuchKey
+	var notFound *s3types.NotFound
+	return errors.As(err, &noSuchKey) || errors.As(err, &notFound)
+}
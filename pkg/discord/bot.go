@@ -19,6 +19,7 @@ import (
 	"drigo/pkg/discord/handlers"
 	"drigo/pkg/drigo"
 	"drigo/pkg/sqlite"
+	"drigo/pkg/types"
 	"drigo/pkg/utils"
 )
 
@@ -55,6 +56,8 @@ type Bot interface {
 	Session() *discordgo.Session
 	Ready() <-chan struct{}
 	SendDM(userID, postID string) error
+	// OnPostChanged registers fn to run after a Discord command changes a post.
+	OnPostChanged(fn func(post *types.Post, deleted bool))
 }
 
 func New(cfg *Config) (Bot, error) {
@@ -111,6 +114,10 @@ func (b *botImpl) SendDM(userID, postID string) error {
 	return b.config.DrigoBot.SendDirectMessage(userID, postID)
 }
 
+func (b *botImpl) OnPostChanged(fn func(post *types.Post, deleted bool)) {
+	b.config.DrigoBot.OnPostChanged(fn)
+}
+
 func (b *botImpl) Stop() error {
 	b.stopOnce.Do(func() {
 		if b.config.Cancel != nil {
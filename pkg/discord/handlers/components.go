@@ -17,7 +17,7 @@ const (
 
 	readmoreDismiss Component = "readmore_dismiss"
 
-	paginationButtons Component = "pagination_button"
+	PaginationButtons Component = "pagination_button"
 	okCancelButtons   Component = "ok_cancel_buttons"
 
 	Cancel    Component = "cancel"
@@ -117,17 +117,17 @@ var Components = map[Component]discordgo.MessageComponent{
 		},
 	},
 
-	paginationButtons: discordgo.ActionsRow{
+	PaginationButtons: discordgo.ActionsRow{
 		Components: []discordgo.MessageComponent{
 			discordgo.Button{
 				Label:    "Previous",
 				Style:    discordgo.SecondaryButton,
-				CustomID: paginationButtons + "_previous",
+				CustomID: PaginationButtons + "_previous",
 			},
 			discordgo.Button{
 				Label:    "Next",
 				Style:    discordgo.SecondaryButton,
-				CustomID: paginationButtons + "_next",
+				CustomID: PaginationButtons + "_next",
 			},
 		},
 	},
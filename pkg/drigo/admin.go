@@ -0,0 +1,369 @@
+package drigo
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+
+	"drigo/pkg/discord/handlers"
+	"drigo/pkg/sqlite"
+	"drigo/pkg/types"
+	"drigo/pkg/utils"
+)
+
+const (
+	AdminCommand = "aegis"
+
+	adminEdit   = "edit"
+	adminDelete = "delete"
+	adminRoles  = "roles"
+	adminList   = "list"
+
+	adminPost = "post"
+	adminPage = "page"
+
+	// adminRolesModal prefixes the custom ID of the /aegis roles modal.
+	adminRolesModal = "aegis_roles:"
+
+	adminListPageSize = 10
+)
+
+// adminPostOption is the autocompleted post picker shared by the subcommands.
+var adminPostOption = &discordgo.ApplicationCommandOption{
+	Type:         discordgo.ApplicationCommandOptionString,
+	Name:         adminPost,
+	Description:  "Post to manage",
+	Required:     true,
+	Autocomplete: true,
+}
+
+func adminCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        AdminCommand,
+		Description: "Manage Aegis posts",
+		Type:        discordgo.ChatApplicationCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        adminEdit,
+				Description: "Change the title or description of a post",
+				Options: []*discordgo.ApplicationCommandOption{
+					adminPostOption,
+					commandOption[postTitle],
+					commandOption[postDescription],
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        adminDelete,
+				Description: "Delete a post and its announcements",
+				Options:     []*discordgo.ApplicationCommandOption{adminPostOption},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        adminRoles,
+				Description: "Change which roles can view a post",
+				Options:     []*discordgo.ApplicationCommandOption{adminPostOption},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        adminList,
+				Description: "List the latest posts",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        adminPage,
+						Description: "Page number",
+						Required:    false,
+						MinValue:    new(1.),
+					},
+				},
+			},
+		},
+	}
+}
+
+// isAdmin reports whether the user behind an interaction is an Aegis admin,
+// the same flag the web API checks.
+func (q *Bot) isAdmin(i *discordgo.Interaction) bool {
+	user := utils.GetUser(i)
+	if user == nil {
+		return false
+	}
+	u, err := q.db.UserByID(user.ID)
+	return err == nil && u != nil && u.IsAdmin
+}
+
+func (q *Bot) handleAdmin(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if !q.isAdmin(i.Interaction) {
+		return handlers.ErrorEphemeral(s, i.Interaction, "Only Aegis admins can use this command.")
+	}
+	data := i.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		return handlers.ErrorEphemeral(s, i.Interaction, "Missing subcommand.")
+	}
+	sub := data.Options[0]
+	opts := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(sub.Options))
+	for _, opt := range sub.Options {
+		opts[opt.Name] = opt
+	}
+
+	if sub.Name == adminList {
+		page := 1
+		if opt, ok := opts[adminPage]; ok {
+			page = max(int(opt.IntValue()), 1)
+		}
+		return q.handleAdminList(s, i, page)
+	}
+
+	opt, ok := opts[adminPost]
+	if !ok {
+		return handlers.ErrorEphemeral(s, i.Interaction, "Missing post.")
+	}
+	post, err := q.db.ReadPostByExternalID(strings.TrimSpace(opt.StringValue()))
+	if err != nil {
+		return handlers.ErrorEphemeral(s, i.Interaction, "Post not found.")
+	}
+
+	switch sub.Name {
+	case adminEdit:
+		return q.handleAdminEdit(s, i, post, opts)
+	case adminDelete:
+		return q.handleAdminDelete(s, i, post)
+	case adminRoles:
+		return q.handleAdminRoles(s, i, post)
+	}
+	return handlers.ErrorEphemeral(s, i.Interaction, "Unknown subcommand.")
+}
+
+func (q *Bot) handleAdminEdit(s *discordgo.Session, i *discordgo.InteractionCreate, post *types.Post, opts map[string]*discordgo.ApplicationCommandInteractionDataOption) error {
+	var patch sqlite.PostPatch
+	if opt, ok := opts[postTitle]; ok {
+		patch.Title = new(opt.StringValue())
+	}
+	if opt, ok := opts[postDescription]; ok {
+		patch.Description = new(opt.StringValue())
+	}
+	if patch.Title == nil && patch.Description == nil {
+		return handlers.ErrorEphemeral(s, i.Interaction, "Give a new title or description.")
+	}
+
+	if err := handlers.EphemeralThink(s, i); err != nil {
+		return err
+	}
+	if err := q.db.PatchPost(post.ID, patch); err != nil {
+		return handlers.ErrorEdit(s, i.Interaction, "Failed to update post", err)
+	}
+	updated, err := q.db.ReadPost(post.ID)
+	if err != nil {
+		return handlers.ErrorEdit(s, i.Interaction, "Failed to read updated post", err)
+	}
+	q.audit(i.Interaction, AdminCommand+" "+adminEdit, types.AuditPostUpdate, post.PostKey, types.AuditDiff(post.AuditFields(), updated.AuditFields()))
+	q.postChanged(updated, false)
+
+	log.Info("Post updated from Discord", "id", post.PostKey, "by", utils.GetUsername(i.Interaction))
+	_, err = handlers.EditInteractionResponse(s, i.Interaction, fmt.Sprintf("Updated **%s**.", postName(updated)))
+	return err
+}
+
+func (q *Bot) handleAdminDelete(s *discordgo.Session, i *discordgo.InteractionCreate, post *types.Post) error {
+	if err := handlers.EphemeralThink(s, i); err != nil {
+		return err
+	}
+	if err := q.db.DeletePost(post.ID); err != nil {
+		return handlers.ErrorEdit(s, i.Interaction, "Failed to delete post", err)
+	}
+	q.postChanged(post, true)
+	q.audit(i.Interaction, AdminCommand+" "+adminDelete, types.AuditPostDelete, post.PostKey, nil)
+
+	log.Info("Post deleted from Discord", "id", post.PostKey, "by", utils.GetUsername(i.Interaction))
+	_, err := handlers.EditInteractionResponse(s, i.Interaction, fmt.Sprintf("Deleted **%s**.", postName(post)))
+	return err
+}
+
+// handleAdminRoles opens a role picker preselected with the post's roles.
+func (q *Bot) handleAdminRoles(s *discordgo.Session, i *discordgo.InteractionCreate, post *types.Post) error {
+	defaults := make([]discordgo.SelectMenuDefaultValue, 0, len(post.AllowedRoles))
+	for _, r := range post.AllowedRoles {
+		defaults = append(defaults, discordgo.SelectMenuDefaultValue{ID: r.RoleID, Type: discordgo.SelectMenuDefaultValueRole})
+	}
+	picker := components[roleSelect].(discordgo.SelectMenu)
+	picker.DefaultValues = defaults
+
+	return handlers.Wrap(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: adminRolesModal + post.PostKey,
+			Title:    "Post roles",
+			Components: []discordgo.MessageComponent{
+				discordgo.Label{
+					Label:       "Allowed roles",
+					Description: "Leave empty to make the post visible to everyone.",
+					Component:   picker,
+				},
+			},
+			Flags: discordgo.MessageFlagsIsComponentsV2,
+		},
+	}))
+}
+
+func (q *Bot) handleAdminRolesSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	if !q.isAdmin(i.Interaction) {
+		return handlers.ErrorEphemeral(s, i.Interaction, "Only Aegis admins can use this command.")
+	}
+	data := i.ModalSubmitData()
+	post, err := q.db.ReadPostByExternalID(strings.TrimPrefix(data.CustomID, adminRolesModal))
+	if err != nil {
+		return handlers.ErrorEphemeral(s, i.Interaction, "Post not found.")
+	}
+
+	var roles []*types.Allowed
+	for _, c := range data.Components {
+		lbl, ok := c.(*discordgo.Label)
+		if !ok {
+			continue
+		}
+		if sm, ok := lbl.Component.(*discordgo.SelectMenu); ok && sm.CustomID == roleSelect {
+			for _, id := range sm.Values {
+				role := data.Resolved.Roles[id]
+				if role == nil {
+					role, _ = s.State.Role(i.GuildID, id)
+				}
+				if role == nil {
+					role = &discordgo.Role{ID: id}
+				}
+				roles = append(roles, new(allowedFromRole(role)))
+			}
+		}
+	}
+
+	if err := handlers.EphemeralThink(s, i); err != nil {
+		return err
+	}
+	patch := sqlite.PostPatch{ReplaceAllowedRoles: roles, ClearAllowedRoles: len(roles) == 0}
+	if err := q.db.PatchPost(post.ID, patch); err != nil {
+		return handlers.ErrorEdit(s, i.Interaction, "Failed to update roles", err)
+	}
+	updated, err := q.db.ReadPost(post.ID)
+	if err != nil {
+		return handlers.ErrorEdit(s, i.Interaction, "Failed to read updated post", err)
+	}
+	q.audit(i.Interaction, AdminCommand+" "+adminRoles, types.AuditPostUpdate, post.PostKey, types.AuditDiff(post.AuditFields(), updated.AuditFields()))
+	q.postChanged(updated, false)
+
+	log.Info("Post roles updated from Discord", "id", post.PostKey, "roles", len(roles), "by", utils.GetUsername(i.Interaction))
+	if len(roles) == 0 {
+		_, err = handlers.EditInteractionResponse(s, i.Interaction, fmt.Sprintf("**%s** is now visible to everyone.", postName(updated)))
+		return err
+	}
+	_, err = handlers.EditInteractionResponse(s, i.Interaction, fmt.Sprintf("Updated roles of **%s**.\n%s", postName(updated), rolesContent(updated.AllowedRoles)))
+	return err
+}
+
+func (q *Bot) handleAdminList(s *discordgo.Session, i *discordgo.InteractionCreate, page int) error {
+	if err := handlers.EphemeralThink(s, i); err != nil {
+		return err
+	}
+	posts, err := q.db.ListPosts(sqlite.ListOptions{
+		Limit:  adminListPageSize,
+		Offset: (page - 1) * adminListPageSize,
+		Sort:   "date",
+	})
+	if err != nil {
+		return handlers.ErrorEdit(s, i.Interaction, "Failed to list posts", err)
+	}
+	if len(posts) == 0 {
+		_, err = handlers.EditInteractionResponse(s, i.Interaction, "No posts on this page.")
+		return err
+	}
+
+	var sb strings.Builder
+	for _, p := range posts {
+		fmt.Fprintf(&sb, "**%s** `%s` · %d image(s)", postName(p), p.PostKey, len(p.Images))
+		if len(p.AllowedRoles) > 0 {
+			sb.WriteString(" ·")
+			for _, r := range p.AllowedRoles {
+				sb.WriteString(" <@&")
+				sb.WriteString(r.RoleID)
+				sb.WriteString(">")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	_, err = handlers.EditInteractionResponse(s, i.Interaction, discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("Posts, page %d", page),
+		Description: sb.String(),
+	})
+	return err
+}
+
+// handleAdminAutocomplete suggests posts by title for the post option. Only
+// admins get suggestions.
+func (q *Bot) handleAdminAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	if q.isAdmin(i.Interaction) {
+		var query string
+		for _, sub := range i.ApplicationCommandData().Options {
+			for _, opt := range sub.Options {
+				if opt.Focused {
+					query = strings.TrimSpace(opt.StringValue())
+				}
+			}
+		}
+		refs, err := q.db.PostTitles(query, 25)
+		if err != nil {
+			log.Error("Failed to suggest posts", "error", err)
+		}
+		for _, ref := range refs {
+			name := []rune(fmt.Sprintf("%s (%s)", cmp.Or(ref.Title, "Untitled"), ref.PostKey))
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  string(name[:min(len(name), 100)]),
+				Value: ref.PostKey,
+			})
+		}
+	}
+	return handlers.Wrap(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	}))
+}
+
+// rolesContent is the message content listing the roles that can view a post.
+func rolesContent(roles []types.Allowed) string {
+	if len(roles) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("> Allowed roles:\n")
+	for _, r := range roles {
+		sb.WriteString("> <@&")
+		sb.WriteString(r.RoleID)
+		sb.WriteString(">\n")
+	}
+	return sb.String()
+}
+
+// postName is how a post is referred to in replies.
+func postName(p *types.Post) string {
+	return cmp.Or(p.Title, p.PostKey)
+}
+
+// allowedFromRole converts a Discord role into the Allowed record stored with posts.
+func allowedFromRole(role *discordgo.Role) types.Allowed {
+	return types.Allowed{
+		RoleID:       role.ID,
+		Name:         role.Name,
+		Managed:      role.Managed,
+		Mentionable:  role.Mentionable,
+		Hoist:        role.Hoist,
+		Color:        role.Color,
+		Position:     role.Position,
+		Permissions:  role.Permissions,
+		Icon:         role.Icon,
+		UnicodeEmoji: role.UnicodeEmoji,
+		Flags:        int(role.Flags),
+	}
+}
@@ -1,11 +1,17 @@
 package drigo
 
 import (
+	"fmt"
+
 	"github.com/bwmarrin/discordgo"
 )
 
 const (
-	PostImageCommand = "post"
+	PostImageCommand   = "post"
+	SaveMessageCommand = "Save to Aegis"
+
+	// maxPostAttachments caps the media of a /post, counting the full image.
+	maxPostAttachments = 10
 )
 
 func (q *Bot) commands() []*discordgo.ApplicationCommand {
@@ -14,15 +20,21 @@ func (q *Bot) commands() []*discordgo.ApplicationCommand {
 			Name:        PostImageCommand,
 			Description: "Publish an image to subscribers",
 			Type:        discordgo.ChatApplicationCommand,
-			Options: []*discordgo.ApplicationCommandOption{
+			Options: append([]*discordgo.ApplicationCommandOption{
 				commandOption[fullImage],
 				commandOption[thumbnailImage],
 				commandOption[postTitle],
 				commandOption[postDescription],
 				commandOption[roleSelect],
 				commandOption[channelSelect],
-			},
+			}, extraImageOptions()...),
 		},
+		{
+			Name: SaveMessageCommand,
+			Type: discordgo.MessageApplicationCommand,
+		},
+		adminCommand(),
+		galleryCommand(),
 	}
 }
 
@@ -78,3 +90,22 @@ var commandOption = map[string]*discordgo.ApplicationCommandOption{
 		MaxLength:   2000,
 	},
 }
+
+// extraImage names the nth attachment option of /post; the first is fullImage.
+func extraImage(n int) string {
+	return fmt.Sprintf("%s_%d", fullImage, n)
+}
+
+// extraImageOptions returns the optional attachment options after the full image.
+func extraImageOptions() []*discordgo.ApplicationCommandOption {
+	opts := make([]*discordgo.ApplicationCommandOption, 0, maxPostAttachments-1)
+	for n := 2; n <= maxPostAttachments; n++ {
+		opts = append(opts, &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionAttachment,
+			Name:        extraImage(n),
+			Description: fmt.Sprintf("Additional image #%d", n),
+			Required:    false,
+		})
+	}
+	return opts
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/bwmarrin/discordgo"
 
 	"drigo/pkg"
+	"drigo/pkg/access"
 	"drigo/pkg/compositor"
 	"drigo/pkg/discord/handlers"
 	"drigo/pkg/utils"
@@ -31,6 +32,8 @@ func (q *Bot) components() map[string]pkg.Handler {
 	return map[string]pkg.Handler{
 		showImage: q.showImage,
 		sendDM:    q.sendDM,
+
+		handlers.PaginationButtons: q.handleGalleryPage,
 	}
 }
 
@@ -63,6 +66,11 @@ var components = map[string]discordgo.MessageComponent{
 		CustomID: thumbnailModal,
 		Required: new(false),
 	},
+	fullImageModal: discordgo.FileUpload{
+		CustomID:  fullImageModal,
+		MaxValues: maxPostAttachments - 1,
+		Required:  new(false),
+	},
 }
 
 // BuildShowActionsRow returns the two-button row (Show image + Send to DMs) with embedded postKey.
@@ -98,6 +106,66 @@ func buildThumbnailModalLabel() discordgo.Label {
 	}
 }
 
+// buildMoreImagesModalLabel returns the upload for adding images from the modal.
+func buildMoreImagesModalLabel() discordgo.Label {
+	return discordgo.Label{
+		Label:       "More images (optional)",
+		Description: "Added after the attached images, in order.",
+		Component:   components[fullImageModal],
+	}
+}
+
+// buildPostModal returns the modal components for a pending post: role and
+// channel selection, the title and description, and uploads when they fit.
+func buildPostModal(p *types.PendingPost) []discordgo.MessageComponent {
+	comps := []discordgo.MessageComponent{
+		discordgo.Label{
+			Label:       "Select Role",
+			Description: "You can select multiple roles.",
+			Component:   components[roleSelect],
+		},
+		discordgo.Label{
+			Label:       "Select Channel",
+			Description: "You can select multiple channels to crosspost in.",
+			Component:   components[channelSelect],
+		},
+		discordgo.Label{
+			Label:       "Post title (optional)",
+			Description: "Shown above the image.",
+			Component: discordgo.TextInput{
+				CustomID:    postTitle,
+				Style:       discordgo.TextInputShort,
+				Placeholder: "New Image posted!",
+				Required:    new(false),
+				MaxLength:   45,
+				Value:       p.Title,
+			},
+		},
+		discordgo.Label{
+			Label:       "Post description (optional)",
+			Description: "Shown under the title.",
+			Component: discordgo.TextInput{
+				CustomID:    postDescription,
+				Style:       discordgo.TextInputParagraph,
+				Placeholder: "Add a short caption…",
+				Required:    new(false),
+				MaxLength:   2000,
+				Value:       p.Description,
+			},
+		},
+	}
+	if p.NeedsThumbnail {
+		comps = append(comps, buildThumbnailModalLabel())
+	}
+	// Modals hold at most five components, so more images can only be
+	// added here when the thumbnail upload isn't shown.
+	if len(comps) < 5 && len(p.Media) < maxPostAttachments {
+		comps = append(comps, buildMoreImagesModalLabel())
+	}
+	return comps
+
+}
+
 // buildDMOnlyRow returns a single-button row for ephemeral follow-ups.
 func buildDMOnlyRow(postKey string) discordgo.ActionsRow {
 	return discordgo.ActionsRow{
@@ -118,7 +186,7 @@ func (q *Bot) showImage(s *discordgo.Session, i *discordgo.InteractionCreate) er
 		return nil
 	}
 
-	// Prefer postKey encoded in the component CustomID; fallback to in-memory map for legacy messages.
+	// Prefer postKey encoded in the component CustomID; fall back to the recorded message for legacy ones.
 	var postKey string
 	cid := i.MessageComponentData().CustomID
 	if after, ok := strings.CutPrefix(cid, showImage+":"); ok {
@@ -127,9 +195,7 @@ func (q *Bot) showImage(s *discordgo.Session, i *discordgo.InteractionCreate) er
 		postKey = after
 	}
 	if postKey == "" {
-		q.mu.Lock()
-		postKey = q.msgToPost[i.Message.ID]
-		q.mu.Unlock()
+		postKey, _ = q.db.PostKeyByMessageID(i.Message.ID)
 	}
 	if postKey == "" {
 		return handlers.ErrorFollowupEphemeral(s, i.Interaction, "I couldn't link this button to a post.")
@@ -250,20 +316,49 @@ func (q *Bot) prepareEmbed(member *discordgo.Member, p *types.Post, webhookEdit
 	return nil
 }
 
+// interactionMember returns the guild member behind i, looking them up when
+// the interaction did not carry one.
+func interactionMember(s *discordgo.Session, i *discordgo.InteractionCreate) *discordgo.Member {
+	if i.Member != nil || i.GuildID == "" {
+		return i.Member
+	}
+	user := utils.GetUser(i.Member, i.User)
+	if user == nil {
+		return nil
+	}
+	m, err := utils.GetMember(s, i.GuildID, user.ID)
+	if err != nil {
+		return nil
+	}
+	return m
+}
+
 func (q *Bot) isAllowedToView(s *discordgo.Session, i *discordgo.InteractionCreate, p *types.Post) (*discordgo.Member, bool) {
-	member := i.Member
-	if member == nil {
-		if i.GuildID != "" {
-			user := utils.GetUser(i.Member, i.User)
-			if user != nil {
-				if m, err := utils.GetMember(s, i.GuildID, user.ID); err == nil {
-					member = m
-				}
-			}
+	member := interactionMember(s, i)
+
+	// Admins see everything, as on the web, so a deny grant can't lock them out.
+	if q.isAdmin(i.Interaction) {
+		return member, true
+	}
+
+	// Drafts and scheduled posts stay hidden until they go live, archived
+	// posts until restored.
+	if !p.Visible() {
+		return member, false
+	}
+
+	// Per-user grants come next: a deny wins over roles, an allow needs none.
+	if user := utils.GetUser(i.Member, i.User); user != nil {
+		g, err := q.db.ActiveGrant(p.ID, user.ID, time.Now())
+		if err != nil {
+			log.Error("Failed to read post grant", "post", p.ID, "user", user.ID, "error", err)
+		}
+		if g != nil {
+			return member, access.Grant(g, false)
 		}
 	}
 
-	if len(p.AllowedRoles) == 0 {
+	if !access.Gated(p) {
 		return member, true
 	}
 
@@ -272,17 +367,11 @@ func (q *Bot) isAllowedToView(s *discordgo.Session, i *discordgo.InteractionCrea
 		return nil, false
 	}
 
-	roleSet := map[string]struct{}{}
-	for _, r := range member.Roles {
-		roleSet[r] = struct{}{}
-	}
-	for _, ar := range p.AllowedRoles {
-		if _, ok := roleSet[ar.RoleID]; ok {
-			return member, true
-		}
+	tiers, err := q.db.ListTiers()
+	if err != nil {
+		log.Error("Failed to list tiers", "error", err)
 	}
-
-	return member, false
+	return member, access.NewTiers(tiers).CanView(member.Roles, p)
 }
 
 func (q *Bot) sendDM(s *discordgo.Session, i *discordgo.InteractionCreate) error {
@@ -299,9 +388,7 @@ func (q *Bot) sendDM(s *discordgo.Session, i *discordgo.InteractionCreate) error
 		postKey = after
 	}
 	if postKey == "" {
-		q.mu.Lock()
-		postKey = q.msgToPost[i.Message.ID]
-		q.mu.Unlock()
+		postKey, _ = q.db.PostKeyByMessageID(i.Message.ID)
 	}
 	if postKey == "" {
 		return handlers.ErrorFollowupEphemeral(s, i.Interaction, "I couldn't link this button to a post.")
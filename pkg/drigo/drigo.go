@@ -12,6 +12,15 @@ import (
 	"drigo/pkg"
 	"drigo/pkg/bucket"
 	"drigo/pkg/sqlite"
+	"drigo/pkg/types"
+	"drigo/pkg/utils"
+)
+
+const (
+	// pendingTTL is how long a /post command waits for its modal to be submitted.
+	pendingTTL = time.Hour
+	// pendingJanitorInterval is how often expired pending posts are removed.
+	pendingJanitorInterval = 10 * time.Minute
 )
 
 type Bot struct {
@@ -20,21 +29,13 @@ type Bot struct {
 	context    context.Context
 	db         sqlite.DB
 	logger     *log.Logger
-	pending    map[string]*PendingPost // ksuid -> pending post info until modal submit
-	msgToPost  map[string]string       // messageID -> postKey
-	bucket     bucket.Uploader         // optional object storage for large payload fallbacks
+	bucket     bucket.Uploader // optional object storage for large payload fallbacks
+
+	onPostChanged func(post *types.Post, deleted bool)
 }
 
 func (q *Bot) Stop() {
 	q.logger.Info("Stopping drigo bot...")
-	q.mu.Lock()
-	defer q.mu.Unlock()
-	for k := range q.pending {
-		delete(q.pending, k)
-	}
-	for k := range q.msgToPost {
-		delete(q.msgToPost, k)
-	}
 	q.logger.Info("Drigo bot stopped.")
 }
 
@@ -44,48 +45,95 @@ func New(botSession *discordgo.Session, ctx context.Context, db sqlite.DB, logge
 		context:    ctx,
 		db:         db,
 		logger:     logger,
-		pending:    make(map[string]*PendingPost),
-		msgToPost:  make(map[string]string),
 		bucket:     bucket,
 	}
 }
 
+// OnPostChanged registers fn to run after a command creates, edits or deletes
+// a post, so the web app can drop what it cached and bring the post's
+// announcements up to date. It must be set before the bot starts.
+func (q *Bot) OnPostChanged(fn func(post *types.Post, deleted bool)) {
+	q.onPostChanged = fn
+}
+
+func (q *Bot) postChanged(post *types.Post, deleted bool) {
+	if q.onPostChanged != nil {
+		q.onPostChanged(post, deleted)
+	}
+}
+
+// recordMessage remembers that msg announces the post with the given ID, so the
+// web app can edit or remove it later.
+func (q *Bot) recordMessage(postID uint, msg *discordgo.Message) {
+	if msg == nil {
+		return
+	}
+	if err := q.db.AddPostMessage(&types.PostMessage{PostID: postID, ChannelID: msg.ChannelID, MessageID: msg.ID}); err != nil {
+		q.logger.Warn("Failed to record post message", "post", postID, "message", msg.ID, "error", err)
+	}
+}
+
+// audit records a change made to a post through a Discord command, with the
+// invoking user as the actor.
+func (q *Bot) audit(i *discordgo.Interaction, command, action, postKey string, changes map[string]types.AuditChange) {
+	e := &types.AuditEntry{
+		Action:     action,
+		TargetType: "post",
+		TargetID:   postKey,
+		Changes:    changes,
+		Source:     types.AuditSourceDiscord,
+		Path:       "/" + command,
+	}
+	if user := utils.GetUser(i.Member, i.User); user != nil {
+		e.ActorID = user.ID
+		e.ActorName = user.Username
+	}
+	if err := q.db.AddAuditEntry(e); err != nil {
+		q.logger.Warn("Failed to write audit entry", "action", action, "post", postKey, "error", err)
+	}
+}
+
+// forgetPending removes a pending post once its modal has been handled.
+func (q *Bot) forgetPending(postKey string) {
+	if err := q.db.DeletePendingPost(postKey); err != nil {
+		q.logger.Warn("Failed to remove pending post", "key", postKey, "error", err)
+	}
+}
+
 func (q *Bot) SetBucket(b bucket.Uploader) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	q.bucket = b
 }
 
-// PendingPost holds data collected from the slash command until the user completes the modal.
-type PendingPost struct {
-	PostKey        string
-	GuildID        string
-	ChannelID      string
-	Author         *discordgo.User
-	Thumbnail      []byte
-	Full           []byte
-	Filename       string
-	ContentType    string
-	Title          string
-	Description    string
-	NeedsThumbnail bool // true when no thumbnail was provided; a FileUpload label is shown in the modal
-	S3ThumbUrl     string
-}
-
 func (q *Bot) Commands() []*discordgo.ApplicationCommand { return q.commands() }
 func (q *Bot) Handlers() pkg.CommandHandlers             { return q.handlers() }
 func (q *Bot) Components() pkg.Components                { return q.components() }
 
+// Start runs the janitor for abandoned /post commands until the bot's context ends.
 func (q *Bot) Start(botSession *discordgo.Session) {
 	q.botSession = botSession
 
-Polling:
+	ticker := time.NewTicker(pendingJanitorInterval)
+	defer ticker.Stop()
 	for {
+		q.sweepPending()
 		select {
 		case <-q.context.Done():
-			break Polling
-		case <-time.After(1 * time.Second):
-
+			return
+		case <-ticker.C:
 		}
 	}
 }
+
+// sweepPending drops pending posts whose modal was never submitted.
+func (q *Bot) sweepPending() {
+	n, err := q.db.DeleteExpiredPendingPosts(time.Now())
+	if err != nil {
+		q.logger.Error("Failed to remove expired pending posts", "error", err)
+		return
+	}
+	if n > 0 {
+		q.logger.Info("Removed expired pending posts", "count", n)
+	}
+}
@@ -0,0 +1,317 @@
+package drigo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+
+	"drigo/pkg/discord/handlers"
+	"drigo/pkg/sqlite"
+	"drigo/pkg/types"
+	"drigo/pkg/utils"
+)
+
+const (
+	GalleryCommand = "gallery"
+
+	galleryTag    = "tag"
+	galleryAfter  = "after"
+	galleryBefore = "before"
+
+	// galleryPageSize keeps each page within Discord's five action rows: one
+	// row of Show/DM buttons per post plus the pagination row.
+	galleryPageSize = 4
+)
+
+func galleryCommand() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        GalleryCommand,
+		Description: "Browse the posts you can view",
+		Type:        discordgo.ChatApplicationCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         galleryTag,
+				Description:  "Only show posts with this tag",
+				Autocomplete: true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        galleryAfter,
+				Description: "Only show posts on or after this date (YYYY-MM-DD)",
+				MaxLength:   10,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        galleryBefore,
+				Description: "Only show posts on or before this date (YYYY-MM-DD)",
+				MaxLength:   10,
+			},
+		},
+	}
+}
+
+// galleryQuery is the state of a /gallery listing. It travels in the custom ID
+// of the pagination buttons, so the tag is kept by ID to stay well within
+// Discord's 100 character limit.
+type galleryQuery struct {
+	page   int
+	tagID  uint
+	after  time.Time
+	before time.Time // inclusive day
+}
+
+func (g galleryQuery) encode() string {
+	return fmt.Sprintf("%d:%d:%s:%s", g.page, g.tagID, galleryDate(g.after), galleryDate(g.before))
+}
+
+func galleryDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.DateOnly)
+}
+
+func decodeGalleryQuery(state string) (galleryQuery, error) {
+	parts := strings.Split(state, ":")
+	if len(parts) != 4 {
+		return galleryQuery{}, fmt.Errorf("invalid gallery state %q", state)
+	}
+	page, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return galleryQuery{}, err
+	}
+	tagID, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return galleryQuery{}, err
+	}
+	g := galleryQuery{page: max(page, 1), tagID: uint(tagID)}
+	if g.after, err = parseGalleryDate(parts[2]); err != nil {
+		return galleryQuery{}, err
+	}
+	if g.before, err = parseGalleryDate(parts[3]); err != nil {
+		return galleryQuery{}, err
+	}
+	return g, nil
+}
+
+func parseGalleryDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+// handleGallery lists the posts the caller may view, newest first.
+func (q *Bot) handleGallery(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	g := galleryQuery{page: 1}
+	var tag string
+	for _, opt := range i.ApplicationCommandData().Options {
+		var err error
+		switch opt.Name {
+		case galleryTag:
+			tag = strings.TrimSpace(opt.StringValue())
+		case galleryAfter:
+			g.after, err = parseGalleryDate(strings.TrimSpace(opt.StringValue()))
+		case galleryBefore:
+			g.before, err = parseGalleryDate(strings.TrimSpace(opt.StringValue()))
+		}
+		if err != nil {
+			return handlers.ErrorEphemeral(s, i.Interaction, "Dates must look like 2024-01-31.")
+		}
+	}
+
+	if err := handlers.EphemeralThink(s, i); err != nil {
+		return err
+	}
+
+	var tagSlug string
+	if tag != "" {
+		t, err := q.findTag(func(t types.Tag) bool {
+			return t.Slug == tag || strings.EqualFold(t.Name, tag)
+		})
+		if err != nil {
+			return handlers.ErrorEdit(s, i.Interaction, "Failed to read tags", err)
+		}
+		if t == nil {
+			_, err = handlers.EditInteractionResponse(s, i.Interaction, fmt.Sprintf("No tag named %q.", tag))
+			return err
+		}
+		g.tagID, tagSlug = t.ID, t.Slug
+	}
+
+	embed, components, err := q.galleryPage(s, i, g, tagSlug)
+	if err != nil {
+		return handlers.ErrorEdit(s, i.Interaction, "Failed to list posts", err)
+	}
+	_, err = handlers.EditInteractionResponse(s, i.Interaction, embed, components)
+	return err
+}
+
+// handleGalleryPage moves a /gallery listing to the page named by the button.
+func (q *Bot) handleGalleryPage(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	_, state, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	g, err := decodeGalleryQuery(state)
+	if err != nil {
+		return handlers.ErrorEphemeral(s, i.Interaction, "This gallery is no longer valid. Run /gallery again.")
+	}
+
+	var tagSlug string
+	if g.tagID != 0 {
+		t, err := q.findTag(func(t types.Tag) bool { return t.ID == g.tagID })
+		if err != nil || t == nil {
+			return handlers.ErrorEphemeral(s, i.Interaction, "This tag no longer exists. Run /gallery again.")
+		}
+		tagSlug = t.Slug
+	}
+
+	embed, components, err := q.galleryPage(s, i, g, tagSlug)
+	if err != nil {
+		return handlers.ErrorEphemeral(s, i.Interaction, "Failed to list posts", err)
+	}
+	return handlers.UpdateFromComponent(s, i.Interaction, embed, components)
+}
+
+// galleryPage renders one page of posts with a Show and Send to DMs button per
+// post and the pagination buttons. Posts are filtered in the query rather than
+// after it, so every page is full and the offsets line up.
+func (q *Bot) galleryPage(s *discordgo.Session, i *discordgo.InteractionCreate, g galleryQuery, tagSlug string) (discordgo.MessageEmbed, []discordgo.MessageComponent, error) {
+	opts := sqlite.ListOptions{
+		Limit:    galleryPageSize + 1,
+		Offset:   (g.page - 1) * galleryPageSize,
+		Sort:     "date",
+		After:    g.after,
+		Viewable: true,
+	}
+	if !g.before.IsZero() {
+		opts.Before = g.before.AddDate(0, 0, 1)
+	}
+	if tagSlug != "" {
+		opts.Tags = []string{tagSlug}
+	}
+	if member := interactionMember(s, i); member != nil {
+		opts.RoleIDs = member.Roles
+	}
+	if user := utils.GetUser(i.Member, i.User); user != nil {
+		opts.UserID = user.ID
+	}
+
+	posts, err := q.db.ListPosts(opts)
+	if err != nil {
+		return discordgo.MessageEmbed{}, nil, err
+	}
+	hasNext := len(posts) > galleryPageSize
+	posts = posts[:min(len(posts), galleryPageSize)]
+
+	embed := discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Gallery, page %d", g.page),
+	}
+	if tagSlug != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: "#" + tagSlug}
+	}
+	if len(posts) == 0 {
+		embed.Description = "No posts found."
+	}
+
+	var components []discordgo.MessageComponent
+	var sb strings.Builder
+	for n, p := range posts {
+		idx := (g.page-1)*galleryPageSize + n + 1
+		fmt.Fprintf(&sb, "**%d. %s** · <t:%d:d> · %d image(s)\n", idx, postName(p), p.Timestamp.Unix(), len(p.Images))
+		components = append(components, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    fmt.Sprintf("Show %d", idx),
+					Style:    discordgo.PrimaryButton,
+					CustomID: showImage + ":" + p.PostKey,
+				},
+				discordgo.Button{
+					Label:    "Send to DMs",
+					Style:    discordgo.SecondaryButton,
+					CustomID: sendDM + ":" + p.PostKey,
+					Emoji:    &discordgo.ComponentEmoji{Name: "📩"},
+				},
+			},
+		})
+	}
+	if sb.Len() > 0 {
+		embed.Description = sb.String()
+	}
+
+	if g.page > 1 || hasNext {
+		components = append(components, galleryPagination(g, hasNext))
+	}
+	return embed, components, nil
+}
+
+// galleryPagination fills the shared pagination buttons with the state of the
+// neighbouring pages.
+func galleryPagination(g galleryQuery, hasNext bool) discordgo.ActionsRow {
+	row := handlers.Components[handlers.PaginationButtons].(discordgo.ActionsRow)
+	buttons := make([]discordgo.MessageComponent, len(row.Components))
+	for n, c := range row.Components {
+		button := c.(discordgo.Button)
+		target := g
+		switch button.CustomID {
+		case handlers.PaginationButtons + "_previous":
+			target.page--
+			button.Disabled = g.page <= 1
+		case handlers.PaginationButtons + "_next":
+			target.page++
+			button.Disabled = !hasNext
+		}
+		button.CustomID += ":" + target.encode()
+		buttons[n] = button
+	}
+	return discordgo.ActionsRow{Components: buttons}
+}
+
+// findTag returns the first tag matching match, or nil.
+func (q *Bot) findTag(match func(types.Tag) bool) (*types.Tag, error) {
+	tags, err := q.db.ListTags()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		if match(t) {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+// handleGalleryAutocomplete suggests tags for the tag option.
+func (q *Bot) handleGalleryAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	var query string
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Focused {
+			query = strings.ToLower(strings.TrimSpace(opt.StringValue()))
+		}
+	}
+
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	tags, err := q.db.ListTags()
+	if err != nil {
+		log.Error("Failed to suggest tags", "error", err)
+	}
+	for _, t := range tags {
+		if len(choices) == 25 {
+			break
+		}
+		if query != "" && !strings.Contains(strings.ToLower(t.Name), query) && !strings.Contains(t.Slug, query) {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  t.Name,
+			Value: t.Slug,
+		})
+	}
+	return handlers.Wrap(s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	}))
+}
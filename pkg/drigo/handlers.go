@@ -31,9 +31,31 @@ import (
 )
 
 func (q *Bot) processThumbnail(ctx context.Context, data []byte, blur bool, postKey string) ([]byte, string, error) {
+	outBytes, err := encodeThumbnail(data, blur)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(outBytes) > units.DiscordLimit && q.bucket != nil {
+		ts := time.Now().UTC().Format("20060102-150405")
+		key := fmt.Sprintf("%s/%s_thumb.webp", postKey, ts)
+		url, err := q.bucket.Upload(ctx, key, outBytes, "image/webp")
+		if err != nil {
+			log.Error("Failed to upload thumbnail to S3", "error", err)
+			return outBytes, "", nil
+		}
+		return outBytes, url, nil
+	}
+
+	return outBytes, "", nil
+}
+
+// encodeThumbnail re-encodes an image as webp, optionally blurred, shrinking it
+// until it fits Discord's upload limit where possible.
+func encodeThumbnail(data []byte, blur bool) ([]byte, error) {
 	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		return nil, "", fmt.Errorf("decode: %w", err)
+		return nil, fmt.Errorf("decode: %w", err)
 	}
 
 	if blur {
@@ -46,7 +68,7 @@ func (q *Bot) processThumbnail(ctx context.Context, data []byte, blur bool, post
 	for quality >= 80 {
 		var buf bytes.Buffer
 		if err := webp.Encode(&buf, img, webp.Options{Quality: quality}); err != nil {
-			return nil, "", fmt.Errorf("encode webp: %w", err)
+			return nil, fmt.Errorf("encode webp: %w", err)
 		}
 		if buf.Len() <= units.DiscordLimit {
 			outBytes = buf.Bytes()
@@ -58,7 +80,7 @@ func (q *Bot) processThumbnail(ctx context.Context, data []byte, blur bool, post
 	if len(outBytes) == 0 {
 		var buf bytes.Buffer
 		if err := webp.Encode(&buf, img, webp.Options{Quality: 80}); err != nil {
-			return nil, "", fmt.Errorf("encode webp: %w", err)
+			return nil, fmt.Errorf("encode webp: %w", err)
 		}
 		outBytes = buf.Bytes()
 
@@ -77,34 +99,76 @@ func (q *Bot) processThumbnail(ctx context.Context, data []byte, blur bool, post
 
 			var resizeBuf bytes.Buffer
 			if err := webp.Encode(&resizeBuf, img, webp.Options{Quality: 80}); err != nil {
-				return nil, "", fmt.Errorf("encode webp: %w", err)
+				return nil, fmt.Errorf("encode webp: %w", err)
 			}
 			outBytes = resizeBuf.Bytes()
 		}
 	}
 
-	if len(outBytes) > units.DiscordLimit && q.bucket != nil {
-		ts := time.Now().UTC().Format("20060102-150405")
-		key := fmt.Sprintf("%s/%s_thumb.webp", postKey, ts)
-		url, err := q.bucket.Upload(ctx, key, outBytes, "image/webp")
+	return outBytes, nil
+}
+
+// postImages builds one Image per attachment, in order. Like posts from the
+// web uploader, only the first carries the post's thumbnail.
+func postImages(thumbnail []byte, media []types.PendingMedia) []types.Image {
+	images := make([]types.Image, len(media))
+	for i, m := range media {
+		images[i] = types.Image{
+			Blobs: []types.ImageBlob{{Index: 0, Data: m.Data, Filename: m.Filename, ContentType: m.ContentType, Size: int64(len(m.Data))}},
+		}
+	}
+	if len(images) > 0 {
+		images[0].Thumbnail = thumbnail
+	}
+	return images
+}
+
+// previewImages returns what is tiled into a post's embed: its thumbnail and
+// a preview of every later image, blurred when blur is set so restricted
+// media stays preview-only.
+func previewImages(thumbnail []byte, media []types.PendingMedia, blur bool) [][]byte {
+	var previews [][]byte
+	if len(thumbnail) > 0 {
+		previews = append(previews, thumbnail)
+	}
+	for _, m := range media[min(1, len(media)):] {
+		if !strings.HasPrefix(m.ContentType, "image/") {
+			continue
+		}
+		preview, err := encodeThumbnail(m.Data, blur)
 		if err != nil {
-			log.Error("Failed to upload thumbnail to S3", "error", err)
-			return outBytes, "", nil
+			log.Warn("Failed to generate image preview", "filename", m.Filename, "error", err)
+			continue
 		}
-		return outBytes, url, nil
+		previews = append(previews, preview)
 	}
+	return previews
+}
 
-	return outBytes, "", nil
+// previewReaders wraps previews in fresh readers for handlers.EmbedImages.
+func previewReaders(previews [][]byte) []io.Reader {
+	readers := make([]io.Reader, len(previews))
+	for i, p := range previews {
+		readers[i] = &types.ImageReader{Data: p, Reader: bytes.NewReader(p)}
+	}
+	return readers
 }
 
 func (q *Bot) handlers() map[discordgo.InteractionType]map[string]pkg.Handler {
 	return pkg.CommandHandlers{
 		discordgo.InteractionApplicationCommand: {
-			PostImageCommand: q.handlePostImage,
+			PostImageCommand:   q.handlePostImage,
+			SaveMessageCommand: q.handleSaveMessage,
+			AdminCommand:       q.handleAdmin,
+			GalleryCommand:     q.handleGallery,
+		},
+		discordgo.InteractionApplicationCommandAutocomplete: {
+			AdminCommand:   q.handleAdminAutocomplete,
+			GalleryCommand: q.handleGalleryAutocomplete,
 		},
-		discordgo.InteractionApplicationCommandAutocomplete: {},
 		discordgo.InteractionModalSubmit: {
 			PostImageCommand: q.handleModalSubmit,
+			adminRolesModal:  q.handleAdminRolesSubmit,
 		},
 	}
 }
@@ -117,10 +181,11 @@ func (q *Bot) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCr
 		return handlers.ErrorEphemeral(s, i.Interaction, "Missing post key in modal submit")
 	}
 
-	q.mu.Lock()
-	pending, ok := q.pending[postKey]
-	q.mu.Unlock()
-	if !ok || pending == nil {
+	pending, err := q.db.PendingPost(postKey)
+	if err != nil {
+		log.Error("Failed to load pending post", "key", postKey, "error", err)
+	}
+	if pending == nil || len(pending.Media) == 0 {
 		return handlers.ErrorEphemeral(s, i.Interaction, "This post has expired or is invalid. Please try again.")
 	}
 
@@ -130,6 +195,7 @@ func (q *Bot) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCr
 	var selectedChannels []string
 	var title, description string
 	var thumbFromUpload []byte
+	var moreImages []*discordgo.MessageAttachment
 	for _, c := range data.Components {
 		if lbl, ok := c.(*discordgo.Label); ok {
 			if sm, ok := lbl.Component.(*discordgo.SelectMenu); ok {
@@ -159,6 +225,13 @@ func (q *Bot) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCr
 					}
 				}
 			}
+			if fileUpload, ok := lbl.Component.(*discordgo.FileUpload); ok && fileUpload.CustomID == fullImageModal {
+				for _, attachID := range fileUpload.Values {
+					if att, found := data.Resolved.Attachments[attachID]; found {
+						moreImages = append(moreImages, att)
+					}
+				}
+			}
 		}
 	}
 	if len(selectedRoles) == 0 {
@@ -169,27 +242,69 @@ func (q *Bot) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCr
 		return err
 	}
 
-	thumbnail := append([]byte(nil), pending.Thumbnail...)
+	thumbnail := pending.Thumbnail
 	if len(thumbFromUpload) > 0 {
 		thumbnail = thumbFromUpload
 	}
 
+	// Media imported from a message is only downloaded once the modal is submitted.
+	media := make([]types.PendingMedia, 0, len(pending.Media)+len(moreImages))
+	for _, m := range pending.Media {
+		if len(m.Data) == 0 && m.SourceURL != "" {
+			data, err := utils.GetDataFromUrl(m.SourceURL)
+			if err != nil {
+				log.Warn("Failed to download message attachment", "filename", m.Filename, "error", err)
+				continue
+			}
+			m.Data = data
+			m.Size = int64(len(data))
+		}
+		media = append(media, m)
+	}
+	for _, att := range moreImages {
+		if len(media) >= maxPostAttachments {
+			break
+		}
+		if !strings.HasPrefix(att.ContentType, "image/") && !strings.HasPrefix(att.ContentType, "video/") {
+			continue
+		}
+		data, err := utils.GetDataFromUrl(att.URL)
+		if err != nil {
+			log.Warn("Failed to download modal attachment", "filename", att.Filename, "error", err)
+			continue
+		}
+		media = append(media, types.PendingMedia{Filename: att.Filename, ContentType: att.ContentType, Data: data})
+	}
+	if len(media) == 0 {
+		return handlers.ErrorEdit(s, i.Interaction, "Failed to download the attachments for this post.")
+	}
+	// A generated thumbnail is blurred for the roles; one from the pending
+	// post wasn't.
+	var blurred bool
+	if len(thumbnail) == 0 && strings.HasPrefix(media[0].ContentType, "image/") {
+		if t, err := encodeThumbnail(media[0].Data, len(selectedRoles) > 0); err != nil {
+			log.Warn("Failed to generate thumbnail", "key", postKey, "error", err)
+		} else {
+			thumbnail = t
+			blurred = len(selectedRoles) > 0
+		}
+	}
+
 	// Build post record
 	now := time.Now().UTC()
+	timestamp := now
+	if !pending.PostedAt.IsZero() {
+		timestamp = pending.PostedAt.UTC()
+	}
 	post := &types.Post{
 		PostKey:     postKey,
 		ChannelID:   pending.ChannelID,
 		GuildID:     pending.GuildID,
 		Title:       pending.Title,
 		Description: pending.Description,
-		Timestamp:   now,
+		Timestamp:   timestamp,
 		IsPremium:   true,
-		Images: []types.Image{{
-			Thumbnail: thumbnail,
-			Blobs: []types.ImageBlob{
-				{Index: 0, Data: append([]byte(nil), pending.Full...), Filename: pending.Filename, ContentType: pending.ContentType, Size: int64(len(pending.Full))},
-			},
-		}},
+		Images:      postImages(thumbnail, media),
 	}
 
 	if pending.Author != nil {
@@ -213,10 +328,15 @@ func (q *Bot) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCr
 		}
 		post.AllowedRoles = append(post.AllowedRoles, types.Allowed{RoleID: rid})
 	}
+	if len(post.Images) > 0 {
+		post.Images[0].ThumbnailBlurred = blurred
+	}
 
 	if err := q.db.CreatePost(post); err != nil {
 		return handlers.ErrorEdit(s, i.Interaction, "Failed to save post", err)
 	}
+	q.postChanged(post, false)
+	q.audit(i.Interaction, PostImageCommand, types.AuditPostCreate, post.PostKey, types.AuditDiff(nil, post.AuditFields()))
 
 	var sb strings.Builder
 	var contentPtr *string
@@ -241,7 +361,7 @@ func (q *Bot) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCr
 	embed := &discordgo.MessageEmbed{
 		Title:       post.Title,
 		Type:        discordgo.EmbedTypeImage,
-		Timestamp:   time.Now().Format(time.RFC3339),
+		Timestamp:   post.Timestamp.Format(time.RFC3339),
 		Description: post.Description,
 	}
 	if embed.Description == "" {
@@ -255,9 +375,9 @@ func (q *Bot) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCr
 			URL:     "https://discord.com/users/" + pending.Author.ID,
 		}
 	}
+	previews := previewImages(thumbnail, media, len(post.AllowedRoles) > 0)
 	if len(selectedChannels) == 0 {
-		thumbR := &types.ImageReader{Data: thumbnail, Reader: bytes.NewReader(thumbnail)}
-		if err := handlers.EmbedImages(webhookEdit, embed, nil, []io.Reader{thumbR}, compositor.Compositor[*types.MemberExif](nil)); err != nil {
+		if err := handlers.EmbedImages(webhookEdit, embed, nil, previewReaders(previews), compositor.Compositor[*types.MemberExif](nil)); err != nil {
 			return handlers.ErrorEdit(s, i.Interaction, fmt.Errorf("error creating image embed: %w", err))
 		}
 
@@ -266,10 +386,8 @@ func (q *Bot) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCr
 			return handlers.ErrorFollowupEphemeral(s, i.Interaction, "Failed to send response", err)
 		}
 
-		q.mu.Lock()
-		q.msgToPost[msg.ID] = postKey
-		delete(q.pending, postKey)
-		q.mu.Unlock()
+		q.recordMessage(post.ID, msg)
+		q.forgetPending(postKey)
 		return nil
 	}
 
@@ -280,8 +398,7 @@ func (q *Bot) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCr
 		}
 		perEmbed := *embed
 		var perEdit discordgo.WebhookEdit
-		thumbR := &types.ImageReader{Data: thumbnail, Reader: bytes.NewReader(thumbnail)}
-		if err := handlers.EmbedImages(&perEdit, &perEmbed, nil, []io.Reader{thumbR}, compositor.Compositor[*types.MemberExif](nil)); err != nil {
+		if err := handlers.EmbedImages(&perEdit, &perEmbed, nil, previewReaders(previews), compositor.Compositor[*types.MemberExif](nil)); err != nil {
 			continue
 		}
 		msg, err := s.ChannelMessageSendComplex(chID, &discordgo.MessageSend{
@@ -295,9 +412,7 @@ func (q *Bot) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCr
 			continue
 		}
 		log.Debug("Posted in selected channel", "channel", chID, "msg", msg.ID)
-		q.mu.Lock()
-		q.msgToPost[msg.ID] = postKey
-		q.mu.Unlock()
+		q.recordMessage(post.ID, msg)
 		sentCount++
 	}
 	if sentCount == 0 {
@@ -309,13 +424,37 @@ func (q *Bot) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCr
 		return handlers.ErrorFollowupEphemeral(s, i.Interaction, "Posted, but failed to update the response.", err)
 	}
 
-	q.mu.Lock()
-	delete(q.pending, postKey)
-	q.mu.Unlock()
+	q.forgetPending(postKey)
 
 	return nil
 }
 
+// openPostModal saves p and answers the interaction with the role and channel
+// selection modal for it. The pending post is saved first so the submit can
+// find it even after a restart.
+func (q *Bot) openPostModal(s *discordgo.Session, i *discordgo.InteractionCreate, p *types.PendingPost) error {
+	p.ExpiresAt = time.Now().UTC().Add(pendingTTL)
+	if err := q.db.CreatePendingPost(p); err != nil {
+		return handlers.ErrorEphemeral(s, i.Interaction, "Failed to prepare your post. Please try again.", err)
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   PostImageCommand + p.PostKey,
+			Title:      "Post Image",
+			Components: buildPostModal(p),
+			Flags:      discordgo.MessageFlagsIsComponentsV2,
+		},
+	})
+	if err != nil {
+		log.Error("Error responding with modal", "error", err)
+		q.forgetPending(p.PostKey)
+		return handlers.ErrorEdit(s, i.Interaction, "Failed to open role selection modal", err)
+	}
+	return nil
+}
+
 func (q *Bot) handlePostImage(s *discordgo.Session, i *discordgo.InteractionCreate) error {
 	now := time.Now()
 	user := utils.GetUser(i.Interaction.Member)
@@ -364,20 +503,36 @@ func (q *Bot) handlePostImage(s *discordgo.Session, i *discordgo.InteractionCrea
 		}
 	}
 
-	var thumbBytes, fullBytes []byte
-	var filename string
+	var thumbBytes []byte
 
-	// Get Full Image
-	if option, ok := optionMap[fullImage]; ok {
+	// Get the full image and any additional attachments, in option order
+	var media []types.PendingMedia
+	for n := 1; n <= maxPostAttachments; n++ {
+		name := fullImage
+		if n > 1 {
+			name = extraImage(n)
+		}
+		option, ok := optionMap[name]
+		if !ok {
+			continue
+		}
 		att, ok := attachments[option.Value.(string)]
 		if !ok {
-			return handlers.ErrorEdit(s, i.Interaction, "You need to provide a full image.")
+			if n == 1 {
+				return handlers.ErrorEdit(s, i.Interaction, "You need to provide a full image.")
+			}
+			continue
 		}
-		fullBytes = append([]byte(nil), att.Image.Bytes()...)
-		filename = att.Attachment.Filename
-	} else {
+		media = append(media, types.PendingMedia{
+			Filename:    att.Attachment.Filename,
+			ContentType: att.Attachment.ContentType,
+			Data:        append([]byte(nil), att.Image.Bytes()...),
+		})
+	}
+	if len(media) == 0 {
 		return handlers.ErrorEdit(s, i.Interaction, "Full image is missing.")
 	}
+	fullBytes := media[0].Data
 
 	postKey := ksuid.New().String()
 	var finalS3ThumbUrl string
@@ -418,78 +573,18 @@ func (q *Bot) handlePostImage(s *discordgo.Session, i *discordgo.InteractionCrea
 
 	if _, ok := optionMap[roleSelect]; !ok {
 		log.Debugf("Responding with a modal in %s", time.Since(now))
-		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseModal,
-			Data: &discordgo.InteractionResponseData{
-				CustomID: PostImageCommand + postKey,
-				Title:    "Post Image",
-				Components: func() []discordgo.MessageComponent {
-					comps := []discordgo.MessageComponent{
-						discordgo.Label{
-							Label:       "Select Role",
-							Description: "You can select multiple roles.",
-							Component:   components[roleSelect],
-						},
-						discordgo.Label{
-							Label:       "Select Channel",
-							Description: "You can select multiple channels to crosspost in.",
-							Component:   components[channelSelect],
-						},
-						discordgo.Label{
-							Label:       "Post title (optional)",
-							Description: "Shown above the image.",
-							Component: discordgo.TextInput{
-								CustomID:    postTitle,
-								Style:       discordgo.TextInputShort,
-								Placeholder: "New Image posted!",
-								Required:    new(false),
-								MaxLength:   45,
-								Value:       titleVal,
-							},
-						},
-						discordgo.Label{
-							Label:       "Post description (optional)",
-							Description: "Shown under the title.",
-							Component: discordgo.TextInput{
-								CustomID:    postDescription,
-								Style:       discordgo.TextInputParagraph,
-								Placeholder: "Add a short caption…",
-								Required:    new(false),
-								MaxLength:   2000,
-								Value:       descVal,
-							},
-						},
-					}
-					if needsThumbnail {
-						comps = append(comps, buildThumbnailModalLabel())
-					}
-					return comps
-				}(),
-				Flags: discordgo.MessageFlagsIsComponentsV2,
-			},
-		})
-		if err != nil {
-			log.Error("Error responding with modal", "error", err)
-			return handlers.ErrorEdit(s, i.Interaction, "Failed to open role selection modal", err)
-		}
-
-		q.mu.Lock()
-		q.pending[postKey] = &PendingPost{
+		return q.openPostModal(s, i, &types.PendingPost{
 			PostKey:        postKey,
 			GuildID:        i.GuildID,
 			ChannelID:      i.ChannelID,
 			Author:         utils.GetUser(i.Interaction),
 			Thumbnail:      thumbBytes,
-			Full:           fullBytes,
-			Filename:       filename,
+			Media:          media,
 			Title:          titleVal,
 			Description:    descVal,
 			NeedsThumbnail: needsThumbnail,
-			S3ThumbUrl:     finalS3ThumbUrl,
-		}
-		q.mu.Unlock()
-
-		return nil
+			S3ThumbURL:     finalS3ThumbUrl,
+		})
 	}
 
 	role := optionMap[roleSelect].RoleValue(s, i.GuildID)
@@ -507,10 +602,7 @@ func (q *Bot) handlePostImage(s *discordgo.Session, i *discordgo.InteractionCrea
 		Description: descVal,
 		Timestamp:   now,
 		IsPremium:   true,
-		Images: []types.Image{{
-			Thumbnail: append([]byte(nil), thumbBytes...),
-			Blobs:     []types.ImageBlob{{Index: 0, Data: append([]byte(nil), fullBytes...), Filename: filename, ContentType: attachments[optionMap[fullImage].Value.(string)].Attachment.ContentType, Size: int64(len(fullBytes))}},
-		}},
+		Images:      postImages(thumbBytes, media),
 	}
 	if user != nil {
 		author := &types.User{}
@@ -518,24 +610,16 @@ func (q *Bot) handlePostImage(s *discordgo.Session, i *discordgo.InteractionCrea
 		post.Author = author
 	}
 	if role != nil {
-		post.AllowedRoles = []types.Allowed{{
-			RoleID:       role.ID,
-			Name:         role.Name,
-			Managed:      role.Managed,
-			Mentionable:  role.Mentionable,
-			Hoist:        role.Hoist,
-			Color:        role.Color,
-			Position:     role.Position,
-			Permissions:  role.Permissions,
-			Icon:         role.Icon,
-			UnicodeEmoji: role.UnicodeEmoji,
-			Flags:        int(role.Flags),
-		}}
+		post.AllowedRoles = []types.Allowed{allowedFromRole(role)}
 	}
+	// A generated thumbnail was blurred for the role; an uploaded one wasn't.
+	post.Images[0].ThumbnailBlurred = optionMap[thumbnailImage] == nil
 
 	if err := q.db.CreatePost(post); err != nil {
 		return handlers.ErrorEdit(s, i.Interaction, "Failed to save post", err)
 	}
+	q.postChanged(post, false)
+	q.audit(i.Interaction, PostImageCommand, types.AuditPostCreate, post.PostKey, types.AuditDiff(nil, post.AuditFields()))
 
 	webhookEdit := &discordgo.WebhookEdit{
 		Content: nil,
@@ -544,8 +628,8 @@ func (q *Bot) handlePostImage(s *discordgo.Session, i *discordgo.InteractionCrea
 		},
 	}
 
-	thumbR := &types.ImageReader{Data: thumbBytes, Reader: bytes.NewReader(thumbBytes)}
-	if err := handlers.EmbedImages(webhookEdit, embed, nil, []io.Reader{thumbR}, compositor.Compositor[*types.MemberExif](nil)); err != nil {
+	previews := previewImages(thumbBytes, media, role != nil)
+	if err := handlers.EmbedImages(webhookEdit, embed, nil, previewReaders(previews), compositor.Compositor[*types.MemberExif](nil)); err != nil {
 		return handlers.ErrorEdit(s, i.Interaction, fmt.Errorf("error creating image embed: %w", err))
 	}
 
@@ -554,9 +638,57 @@ func (q *Bot) handlePostImage(s *discordgo.Session, i *discordgo.InteractionCrea
 		return handlers.ErrorFollowupEphemeral(s, i.Interaction, "Failed to send response", err)
 	}
 
-	q.mu.Lock()
-	q.msgToPost[msg.ID] = postKey
-	q.mu.Unlock()
+	q.recordMessage(post.ID, msg)
 
 	return nil
 }
+
+// handleSaveMessage imports a message's attachments and text as a post. It
+// opens the same modal as /post, keeping the message's author and time; the
+// attachments are downloaded when the modal is submitted.
+func (q *Bot) handleSaveMessage(s *discordgo.Session, i *discordgo.InteractionCreate) error {
+	data := i.ApplicationCommandData()
+	var msg *discordgo.Message
+	if data.Resolved != nil {
+		msg = data.Resolved.Messages[data.TargetID]
+	}
+	if msg == nil {
+		return handlers.ErrorEphemeral(s, i.Interaction, "I couldn't read that message.")
+	}
+
+	var media []types.PendingMedia
+	for _, att := range msg.Attachments {
+		if len(media) >= maxPostAttachments {
+			break
+		}
+		if !strings.HasPrefix(att.ContentType, "image/") && !strings.HasPrefix(att.ContentType, "video/") {
+			continue
+		}
+		media = append(media, types.PendingMedia{
+			Filename:    att.Filename,
+			ContentType: att.ContentType,
+			Size:        int64(att.Size),
+			SourceURL:   att.URL,
+		})
+	}
+	if len(media) == 0 {
+		return handlers.ErrorEphemeral(s, i.Interaction, "This message has no images or videos to save.")
+	}
+
+	description := msg.Content
+	if runes := []rune(description); len(runes) > 2000 {
+		description = string(runes[:2000])
+	}
+
+	log.Info("Importing message as a post", "message", msg.ID, "attachments", len(media))
+	return q.openPostModal(s, i, &types.PendingPost{
+		PostKey:        ksuid.New().String(),
+		GuildID:        i.GuildID,
+		ChannelID:      i.ChannelID,
+		Author:         msg.Author,
+		Media:          media,
+		Description:    description,
+		NeedsThumbnail: true,
+		PostedAt:       msg.Timestamp,
+	})
+}
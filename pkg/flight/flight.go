@@ -174,6 +174,17 @@ func (p *Cache[K, V]) Delete(k K) {
 	p.fmu.Unlock()
 }
 
+// DeleteFunc removes every cached key for which del returns true.
+func (p *Cache[K, V]) DeleteFunc(del func(K) bool) {
+	p.fmu.Lock()
+	for k := range p.finished {
+		if del(k) {
+			delete(p.finished, k)
+		}
+	}
+	p.fmu.Unlock()
+}
+
 // Expire manually forces the immediate expiration of the strong reference
 // for the given key. The value remains in the cache as a weak reference
 // (if not yet garbage collected), but the strong hold is released.
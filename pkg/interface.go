@@ -2,6 +2,8 @@ package pkg
 
 import (
 	"github.com/bwmarrin/discordgo"
+
+	"drigo/pkg/types"
 )
 
 type Queue interface {
@@ -12,6 +14,10 @@ type Queue interface {
 	Stop()
 
 	SendDirectMessage(userID, postID string) error
+
+	// OnPostChanged registers fn to run after a command creates, edits or
+	// deletes a post.
+	OnPostChanged(fn func(post *types.Post, deleted bool))
 }
 
 type Handler = func(*discordgo.Session, *discordgo.InteractionCreate) error
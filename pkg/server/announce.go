@@ -0,0 +1,255 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+
+	"drigo/pkg/access"
+	"drigo/pkg/drigo"
+	"drigo/pkg/types"
+	"drigo/pkg/units"
+)
+
+// announcement is the Discord message for a post.
+type announcement struct {
+	content    string
+	embed      *discordgo.MessageEmbed
+	components []discordgo.MessageComponent
+	// thumb is attached as thumbName when the embed doesn't link to it elsewhere.
+	thumb     []byte
+	thumbName string
+}
+
+// newAnnouncement builds the message for post. thumb is attached unless
+// thumbURL already points at it; host is the gallery's base URL, and the
+// gallery button is left out without one.
+func newAnnouncement(post *types.Post, thumb []byte, thumbURL string, host string) *announcement {
+	a := &announcement{thumbName: "thumb.webp"}
+	if len(post.Images) > 0 && len(post.Images[0].Blobs) > 0 {
+		a.thumbName = "thumb_" + post.Images[0].Blobs[0].Filename + ".webp"
+	}
+	// Prefer the processed thumbnail for Discord posts so restricted content stays preview-only.
+	if thumbURL == "" {
+		thumbURL = "attachment://" + a.thumbName
+		a.thumb = thumb
+	}
+
+	a.embed = &discordgo.MessageEmbed{
+		Title:       post.Title,
+		Type:        discordgo.EmbedTypeImage,
+		Timestamp:   post.Timestamp.Format(time.RFC3339),
+		Description: post.Description,
+		Image: &discordgo.MessageEmbedImage{
+			URL: thumbURL,
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf(" +%d more", len(post.Images)-1),
+		},
+	}
+	if len(post.Images) <= 1 {
+		a.embed.Footer = nil
+	}
+
+	if a.embed.Description == "" {
+		a.embed.Description = "New post!"
+	}
+	if post.Author != nil {
+		a.embed.Author = &discordgo.MessageEmbedAuthor{
+			Name: post.Author.Username,
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("> Allowed roles:\n")
+	for _, r := range post.AllowedRoles {
+		sb.WriteString("> <@&")
+		sb.WriteString(r.RoleID)
+		sb.WriteString(">\n")
+	}
+	if post.PublicAfter != nil {
+		if access.Public(post, time.Now()) {
+			sb.WriteString("> Now public for everyone\n")
+		} else {
+			fmt.Fprintf(&sb, "> Public for everyone <t:%d:R>\n", post.PublicAfter.Unix())
+		}
+	}
+	a.content = sb.String()
+
+	var messageComponents []discordgo.MessageComponent
+	if host != "" {
+		messageComponents = append(messageComponents, discordgo.Button{
+			Label: "View in Gallery",
+			Style: discordgo.LinkButton,
+			URL:   fmt.Sprintf("%s/post/%s", host, post.PostKey),
+		})
+	}
+	messageComponents = append(messageComponents, drigo.BuildShowActionsRow(post.PostKey).Components...)
+
+	a.components = []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: messageComponents,
+		},
+	}
+	return a
+}
+
+// files returns a fresh reader for the attached thumbnail, if any.
+func (a *announcement) files() []*discordgo.File {
+	if len(a.thumb) == 0 {
+		return nil
+	}
+	return []*discordgo.File{{
+		Name:        a.thumbName,
+		ContentType: "image/webp",
+		Reader:      bytes.NewReader(a.thumb),
+	}}
+}
+
+// postThumbnail loads the stored thumbnail of a post's first image for an
+// announcement, uploading it to the bucket when it is too large to attach.
+func (s *Server) postThumbnail(post *types.Post) ([]byte, string) {
+	if len(post.Images) == 0 || post.Images[0].ThumbnailKey == "" {
+		return nil, ""
+	}
+	thumb, err := s.blobs.Get(s.ctx, post.Images[0].ThumbnailKey)
+	if err != nil {
+		log.Error("Failed to read thumbnail", "id", post.PostKey, "error", err)
+		return nil, ""
+	}
+	if len(thumb) > units.DiscordLimit && s.bucket != nil {
+		key := fmt.Sprintf("%s/%s_thumb.webp", post.PostKey, time.Now().UTC().Format("20060102-150405"))
+		url, err := s.bucket.Upload(s.ctx, key, thumb, "image/webp")
+		if err != nil {
+			log.Error("Failed to upload thumbnail to S3", "error", err)
+			return thumb, ""
+		}
+		return thumb, url
+	}
+	return thumb, ""
+}
+
+// announcePost sends a post's embed to each of its Discord channels and
+// records the messages so later edits and deletes can follow them.
+func (s *Server) announcePost(post *types.Post, thumb []byte, thumbURL string, host string) {
+	a := newAnnouncement(post, thumb, thumbURL, host)
+	for chID := range strings.SplitSeq(post.ChannelID, ",") {
+		chID = strings.TrimSpace(chID)
+		if chID == "" {
+			continue
+		}
+
+		msg, err := s.bot.Session().ChannelMessageSendComplex(chID, &discordgo.MessageSend{
+			Content:    a.content,
+			Embeds:     []*discordgo.MessageEmbed{a.embed},
+			Components: a.components,
+			Files:      a.files(),
+		})
+		if err != nil {
+			log.Error("Failed to send to channel", "channel", chID, "error", err)
+			continue
+		}
+		log.Info("Posted to Discord channel", "channel", chID)
+		if err := s.db.AddPostMessage(&types.PostMessage{PostID: post.ID, ChannelID: chID, MessageID: msg.ID}); err != nil {
+			log.Warn("Failed to record post message", "id", post.PostKey, "message", msg.ID, "error", err)
+		}
+	}
+}
+
+// refreshAnnouncements rewrites every recorded message for post to match its
+// current title, description, roles and thumbnail.
+func (s *Server) refreshAnnouncements(post *types.Post, host string) {
+	msgs, err := s.db.PostMessages(post.ID)
+	if err != nil {
+		log.Error("Failed to read post messages", "id", post.PostKey, "error", err)
+		return
+	}
+	if len(msgs) == 0 {
+		return
+	}
+
+	thumb, thumbURL := s.postThumbnail(post)
+	a := newAnnouncement(post, thumb, thumbURL, host)
+	for _, m := range msgs {
+		_, err := s.bot.Session().ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:          m.MessageID,
+			Channel:     m.ChannelID,
+			Content:     &a.content,
+			Embeds:      &[]*discordgo.MessageEmbed{a.embed},
+			Components:  &a.components,
+			Files:       a.files(),
+			Attachments: &[]*discordgo.MessageAttachment{},
+		})
+		if err != nil {
+			s.forgetMissingMessage(m, err, "Failed to update post message")
+		}
+	}
+}
+
+// removeAnnouncements deletes the recorded messages for a post. Messages the
+// bot can't delete are edited to say the post was removed instead.
+func (s *Server) removeAnnouncements(postID uint) {
+	msgs, err := s.db.PostMessages(postID)
+	if err != nil {
+		log.Error("Failed to read post messages", "post", postID, "error", err)
+		return
+	}
+
+	for _, m := range msgs {
+		err := s.bot.Session().ChannelMessageDelete(m.ChannelID, m.MessageID)
+		if err == nil {
+			if err := s.db.DeletePostMessage(m.MessageID); err != nil {
+				log.Warn("Failed to forget post message", "message", m.MessageID, "error", err)
+			}
+			continue
+		}
+		if s.forgetMissingMessage(m, err, "Failed to delete post message") {
+			continue
+		}
+
+		content := ""
+		_, err = s.bot.Session().ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:      m.MessageID,
+			Channel: m.ChannelID,
+			Content: &content,
+			Embeds: &[]*discordgo.MessageEmbed{{
+				Title:       "Post removed",
+				Description: "This post is no longer available.",
+			}},
+			Components:  &[]discordgo.MessageComponent{},
+			Attachments: &[]*discordgo.MessageAttachment{},
+		})
+		if err != nil {
+			log.Error("Failed to mark post message removed", "channel", m.ChannelID, "message", m.MessageID, "error", err)
+		}
+	}
+}
+
+// discordPostChanged is called by the bot after a command changes a post, so
+// the listing and the post's announcements match what the web app would do.
+func (s *Server) discordPostChanged(post *types.Post, deleted bool) {
+	s.getPostCache.Reset()
+	if deleted {
+		s.removeAnnouncements(post.ID)
+		return
+	}
+	s.refreshAnnouncements(post, s.config.PublicURL)
+}
+
+// forgetMissingMessage drops the record of a message Discord no longer has and
+// reports whether it did; other errors are logged with msg.
+func (s *Server) forgetMissingMessage(m types.PostMessage, err error, msg string) bool {
+	if restErr, ok := errors.AsType[*discordgo.RESTError](err); ok && restErr.Message != nil && restErr.Message.Code == discordgo.ErrCodeUnknownMessage {
+		if err := s.db.DeletePostMessage(m.MessageID); err != nil {
+			log.Warn("Failed to forget post message", "message", m.MessageID, "error", err)
+		}
+		return true
+	}
+	log.Error(msg, "channel", m.ChannelID, "message", m.MessageID, "error", err)
+	return false
+}
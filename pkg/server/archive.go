@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"drigo/pkg/sqlite"
+	"drigo/pkg/types"
+)
+
+// archiveDuePosts archives every post that has expired. Their announcements
+// are deleted when the post asks for it and left alone otherwise.
+func (s *Server) archiveDuePosts() {
+	posts, err := s.db.ArchiveDuePosts(time.Now())
+	if err != nil {
+		log.Error("Failed to archive expired posts", "error", err)
+		return
+	}
+	if len(posts) == 0 {
+		return
+	}
+	s.getPostCache.Reset()
+	for _, post := range posts {
+		log.Info("Archiving expired post", "id", post.PostKey)
+		if post.ExpireAnnouncements {
+			s.removeAnnouncements(post.ID)
+		}
+	}
+}
+
+// restoreRequest is the JSON body of POST /archive/:id/restore.
+type restoreRequest struct {
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// handleGetArchive lists archived posts, most recently archived first.
+func (s *Server) handleGetArchive(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	posts, err := s.db.ArchivedPosts(limit, (page-1)*limit)
+	if err != nil {
+		log.Error("Failed to list archived posts", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list archived posts"})
+	}
+	if posts == nil {
+		posts = []*types.Post{}
+	}
+	return c.JSON(http.StatusOK, posts)
+}
+
+// handleRestorePost brings an archived post back to the gallery, with a new
+// expiry or none. Deleted announcements are not sent again.
+func (s *Server) handleRestorePost(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	var req restoreRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "expiresAt must be in the future"})
+	}
+
+	idStr := c.Param("id")
+	post, err := s.db.ReadPostByExternalID(idStr)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+	}
+	restored, err := s.db.RestorePost(post.ID, req.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sqlite.ErrNotArchived):
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Post is not archived"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+		}
+		log.Error("Failed to restore post", "id", idStr, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to restore post"})
+	}
+	s.getPostCache.Reset()
+	s.wakeScheduler()
+	s.audit(c, types.AuditPostRestore, "post", restored.PostKey, types.AuditDiff(post.AuditFields(), restored.AuditFields()), nil)
+
+	log.Info("Post restored", "id", idStr, "by", user.Username)
+	return c.JSON(http.StatusOK, restored)
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+
+	"drigo/pkg/sqlite"
+	"drigo/pkg/types"
+)
+
+// audit appends an entry for an action taken through the API. detail, when
+// not nil, is stored as JSON.
+func (s *Server) audit(c echo.Context, action, targetType, targetID string, changes map[string]types.AuditChange, detail any) {
+	e := auditEntry(c, action, targetType, targetID)
+	e.Changes = changes
+	if detail != nil {
+		data, err := json.Marshal(detail)
+		if err != nil {
+			log.Warn("Failed to encode audit detail", "action", action, "error", err)
+		}
+		e.Detail = string(data)
+	}
+	s.writeAudit(e)
+}
+
+// auditEntry starts an entry recorded against the caller of c and the
+// request it came in.
+func auditEntry(c echo.Context, action, targetType, targetID string) *types.AuditEntry {
+	req := c.Request()
+	e := &types.AuditEntry{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Source:     types.AuditSourceAPI,
+		IP:         c.RealIP(),
+		UserAgent:  req.UserAgent(),
+		Method:     req.Method,
+		Path:       req.URL.Path,
+	}
+	if claims := GetUserFromContext(c); claims != nil {
+		e.ActorID = claims.UserID
+		e.ActorName = claims.Username
+	}
+	return e
+}
+
+// writeAudit stores e. Failures are only logged, as the action already
+// happened.
+func (s *Server) writeAudit(e *types.AuditEntry) {
+	if err := s.db.AddAuditEntry(e); err != nil {
+		log.Error("Failed to write audit entry", "action", e.Action, "target", e.TargetID, "error", err)
+	}
+}
+
+// handleGetAudit lists audit entries, newest first. They can be filtered by
+// actor, by action (exact, or a prefix ending in "." such as "post."), and by
+// date with since and until, given as RFC 3339 or YYYY-MM-DD (whole day).
+func (s *Server) handleGetAudit(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	q := sqlite.AuditQuery{
+		ActorID: c.QueryParam("actor"),
+		Action:  c.QueryParam("action"),
+		Limit:   limit,
+		Offset:  (page - 1) * limit,
+	}
+	var ok bool
+	if q.Since, ok = parseAuditDate(c.QueryParam("since"), false); !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid since"})
+	}
+	if q.Until, ok = parseAuditDate(c.QueryParam("until"), true); !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid until"})
+	}
+
+	entries, err := s.db.AuditEntries(q)
+	if err != nil {
+		log.Error("Failed to list audit entries", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list audit entries"})
+	}
+	if entries == nil {
+		entries = []types.AuditEntry{}
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+// parseAuditDate reads a date filter. A bare date as an upper bound covers
+// the whole day. An empty value is the zero time.
+func parseAuditDate(value string, end bool) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, true
+	}
+	if t, ok := parseFormTime(value); ok && t != nil {
+		return *t, true
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
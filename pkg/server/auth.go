@@ -126,11 +126,16 @@ func (s *Server) hydrateClaims(claims *JwtCustomClaims) *JwtCustomClaims {
 
 	if userDB, err := s.db.UserByID(claims.UserID); err == nil && userDB != nil {
 		effective.IsAdmin = userDB.IsAdmin
+		// Roles were lost after this token was issued, so its snapshot
+		// can no longer stand in for the live member.
+		if userDB.RolesChangedAt != nil && (claims.IssuedAt == nil || claims.IssuedAt.Before(*userDB.RolesChangedAt)) {
+			effective.Roles = nil
+		}
 	}
 
 	member, err := s.currentGuildMember(claims.UserID)
 	if err != nil {
-		log.Debug("Failed to rehydrate guild member; falling back to JWT roles", "userID", claims.UserID, "error", err)
+		log.Debug("Failed to rehydrate guild member; falling back to JWT roles", "userID", claims.UserID, "roles", len(effective.Roles), "error", err)
 		return &effective
 	}
 
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"drigo/pkg/types"
+)
+
+// collectionRequest is the JSON body for creating or editing a collection.
+// Posts are external post keys in display order.
+type collectionRequest struct {
+	Title       *string  `json:"title"`
+	Description *string  `json:"description"`
+	Roles       []string `json:"roles"`
+	Posts       []string `json:"posts"`
+}
+
+// handleGetCollections lists the collections the caller may view.
+func (s *Server) handleGetCollections(c echo.Context) error {
+	collections, err := s.db.ListCollections()
+	if err != nil {
+		log.Error("Failed to list collections", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list collections"})
+	}
+	user := s.getEffectiveUser(c)
+	settings, _ := s.db.GetSettings()
+	publicAccess := settings != nil && settings.PublicAccess
+	collections = slices.DeleteFunc(collections, func(col *types.Collection) bool {
+		return !publicAccess && !s.canViewCollection(user, col)
+	})
+	if collections == nil {
+		collections = []*types.Collection{}
+	}
+	return c.JSON(http.StatusOK, collections)
+}
+
+func (s *Server) handleGetCollection(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid collection id"})
+	}
+	collection, err := s.db.ReadCollection(uint(id))
+	if err != nil {
+		return collectionErrorResponse(c, "Failed to read collection", err)
+	}
+	settings, _ := s.db.GetSettings()
+	publicAccess := settings != nil && settings.PublicAccess
+	if !publicAccess && !s.canViewCollection(s.getEffectiveUser(c), collection) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Collection not found"})
+	}
+	return c.JSON(http.StatusOK, collection)
+}
+
+// canViewCollection reports whether claims may view col. A collection with
+// its own roles is checked like a post restricted to them, so higher tiers
+// get in too; one gated by its posts is open to anyone who can access at
+// least one of those posts.
+func (s *Server) canViewCollection(claims *JwtCustomClaims, col *types.Collection) bool {
+	if claims != nil && claims.IsAdmin {
+		return true
+	}
+	if col.InheritedRoles {
+		return len(col.Posts) == 0 || slices.ContainsFunc(col.Posts, func(p *types.Post) bool {
+			return s.canAccessPost(claims, p)
+		})
+	}
+	return s.hasPostRole(claims, &types.Post{AllowedRoles: col.AllowedRoles})
+}
+
+func (s *Server) handleCreateCollection(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	var req collectionRequest
+	if err := c.Bind(&req); err != nil || req.Title == nil || strings.TrimSpace(*req.Title) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Missing title"})
+	}
+	postIDs, err := s.resolvePostKeys(req.Posts)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	collection := &types.Collection{
+		Title:        strings.TrimSpace(*req.Title),
+		AllowedRoles: s.parseAllowedRoles(strings.Join(req.Roles, ",")),
+	}
+	if req.Description != nil {
+		collection.Description = *req.Description
+	}
+	if err := s.db.CreateCollection(collection, postIDs); err != nil {
+		return collectionErrorResponse(c, "Failed to create collection", err)
+	}
+
+	created, err := s.db.ReadCollection(collection.ID)
+	if err != nil {
+		return collectionErrorResponse(c, "Failed to read collection", err)
+	}
+	s.audit(c, types.AuditCollectionCreate, "collection", strconv.FormatUint(uint64(created.ID), 10), types.AuditDiff(nil, created.AuditFields()), nil)
+	return c.JSON(http.StatusCreated, created)
+}
+
+// handlePatchCollection edits the title, description and roles of a
+// collection. Omitted fields are left unchanged; "roles": [] clears the
+// collection's own roles so it inherits its posts' again.
+func (s *Server) handlePatchCollection(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid collection id"})
+	}
+	var req collectionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+
+	collection, err := s.db.ReadCollection(uint(id))
+	if err != nil {
+		return collectionErrorResponse(c, "Failed to read collection", err)
+	}
+	before := collection.AuditFields()
+	if collection.InheritedRoles {
+		collection.AllowedRoles = nil
+	}
+	if req.Title != nil {
+		if strings.TrimSpace(*req.Title) == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Missing title"})
+		}
+		collection.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Description != nil {
+		collection.Description = *req.Description
+	}
+	if req.Roles != nil {
+		collection.AllowedRoles = s.parseAllowedRoles(strings.Join(req.Roles, ","))
+	}
+	if err := s.db.UpdateCollection(collection); err != nil {
+		return collectionErrorResponse(c, "Failed to update collection", err)
+	}
+
+	updated, err := s.db.ReadCollection(collection.ID)
+	if err != nil {
+		return collectionErrorResponse(c, "Failed to read collection", err)
+	}
+	s.audit(c, types.AuditCollectionUpdate, "collection", c.Param("id"), types.AuditDiff(before, updated.AuditFields()), nil)
+	return c.JSON(http.StatusOK, updated)
+}
+
+// handleSetCollectionPosts replaces a collection's posts with the given post
+// keys, in order. It is used both to add or remove posts and to reorder them.
+func (s *Server) handleSetCollectionPosts(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid collection id"})
+	}
+	var req collectionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
+	}
+	postIDs, err := s.resolvePostKeys(req.Posts)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	current, err := s.db.ReadCollection(uint(id))
+	if err != nil {
+		return collectionErrorResponse(c, "Failed to read collection", err)
+	}
+	if err := s.db.SetCollectionPosts(uint(id), postIDs); err != nil {
+		return collectionErrorResponse(c, "Failed to update collection", err)
+	}
+
+	updated, err := s.db.ReadCollection(uint(id))
+	if err != nil {
+		return collectionErrorResponse(c, "Failed to read collection", err)
+	}
+	s.audit(c, types.AuditCollectionUpdate, "collection", c.Param("id"), types.AuditDiff(current.AuditFields(), updated.AuditFields()), nil)
+	return c.JSON(http.StatusOK, updated)
+}
+
+func (s *Server) handleDeleteCollection(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid collection id"})
+	}
+	if err := s.db.DeleteCollection(uint(id)); err != nil {
+		return collectionErrorResponse(c, "Failed to delete collection", err)
+	}
+	s.audit(c, types.AuditCollectionDelete, "collection", c.Param("id"), nil, nil)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// resolvePostKeys maps external post keys to their numeric IDs, in order.
+func (s *Server) resolvePostKeys(keys []string) ([]uint, error) {
+	ids := make([]uint, 0, len(keys))
+	for _, key := range keys {
+		post, err := s.db.ReadPostByExternalID(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("post %s not found", key)
+		}
+		ids = append(ids, post.ID)
+	}
+	return ids, nil
+}
+
+// collectionErrorResponse maps collection store errors to a JSON response.
+func collectionErrorResponse(c echo.Context, msg string, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Collection not found"})
+	}
+	log.Error(msg, "error", err)
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": msg})
+}
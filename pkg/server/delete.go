@@ -5,6 +5,8 @@ import (
 
 	"github.com/charmbracelet/log"
 	"github.com/labstack/echo/v4"
+
+	"drigo/pkg/types"
 )
 
 func (s *Server) handleDeletePost(c echo.Context) error {
@@ -36,7 +38,9 @@ func (s *Server) handleDeletePost(c echo.Context) error {
 	}
 
 	s.getPostCache.Reset()
+	s.removeAnnouncements(post.ID)
+	s.audit(c, types.AuditPostDelete, "post", post.PostKey, nil, nil)
 
-	log.Info("Post deleted", "id", idStr, "by", user.Username)
+	log.Info("Post moved to trash", "id", idStr, "by", user.Username)
 	return c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
 }
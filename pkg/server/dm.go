@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+
+	"drigo/pkg/types"
 )
 
 func (s *Server) handlePostDM(c echo.Context) error {
@@ -21,7 +23,7 @@ func (s *Server) handlePostDM(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
 	}
-	if !canAccessPost(user, post) {
+	if !s.canAccessPost(user, post) {
 		return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
 	}
 
@@ -29,6 +31,7 @@ func (s *Server) handlePostDM(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
+	s.audit(c, types.AuditPostDM, "post", post.PostKey, nil, nil)
 
 	return c.JSON(http.StatusOK, map[string]string{"status": "sent"})
 }
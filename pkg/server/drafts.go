@@ -0,0 +1,93 @@
+package server
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"drigo/pkg/sqlite"
+	"drigo/pkg/types"
+)
+
+// handlePublishPost publishes a draft or scheduled post now and announces it.
+func (s *Server) handlePublishPost(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	idStr := c.Param("id")
+	post, err := s.db.ReadPostByExternalID(idStr)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+	}
+
+	post, err = s.db.PublishPost(post.ID, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, sqlite.ErrAlreadyPublished):
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Post is already published"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+		}
+		log.Error("Failed to publish post", "id", idStr, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to publish post"})
+	}
+	s.getPostCache.Reset()
+	s.wakeScheduler()
+
+	s.audit(c, types.AuditPostPublish, "post", post.PostKey, nil, nil)
+	log.Info("Post published", "id", idStr, "by", user.Username)
+	thumb, thumbURL := s.postThumbnail(post)
+	s.announcePost(post, thumb, thumbURL, cmp.Or(s.config.PublicURL, getHost(c)))
+
+	return c.JSON(http.StatusOK, post)
+}
+
+// previewView is what one audience sees of a post once it is published.
+type previewView struct {
+	RoleID  string `json:"roleId,omitempty"`
+	Name    string `json:"name"`
+	CanView bool   `json:"canView"`
+}
+
+// handlePreviewPost shows, for everyone and for each guild role, whether the
+// post would open in full or stay a blurred preview once published. It works
+// for drafts and scheduled posts as well as live ones.
+func (s *Server) handlePreviewPost(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	post, err := s.db.ReadPostByExternalID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+	}
+
+	settings, _ := s.db.GetSettings()
+	publicAccess := settings != nil && settings.PublicAccess
+
+	views := []previewView{{Name: "Everyone", CanView: publicAccess || s.hasPostRole(nil, post)}}
+	if guildData, err := s.guildCache.Get(struct{}{}); err == nil && guildData != nil {
+		for _, role := range guildData.Roles {
+			claims := &JwtCustomClaims{Roles: []*discordgo.Role{{ID: role.ID, Name: role.Name}}}
+			views = append(views, previewView{
+				RoleID:  role.ID,
+				Name:    role.Name,
+				CanView: publicAccess || s.hasPostRole(claims, post),
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"post":  post,
+		"views": views,
+	})
+}
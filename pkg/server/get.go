@@ -2,8 +2,11 @@ package server
 
 import (
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
+	"drigo/pkg/sqlite"
 	"drigo/pkg/types"
 	"github.com/bwmarrin/discordgo"
 	"github.com/charmbracelet/log"
@@ -14,6 +17,9 @@ type sortOption struct {
 	limit  int
 	offset int
 	sort   string
+	// tags holds the comma-joined tag filter, so the key stays comparable.
+	tags    string
+	tagMode string
 }
 
 func (s *Server) handleGetPosts(c echo.Context) error {
@@ -28,7 +34,23 @@ func (s *Server) handleGetPosts(c echo.Context) error {
 	offset := (page - 1) * limit
 	sort := c.QueryParam("sort")
 
-	posts, err := s.getPostCache.Get(sortOption{limit, offset, sort})
+	if q := strings.TrimSpace(c.QueryParam("q")); q != "" {
+		return s.searchPosts(c, q, limit, offset)
+	}
+
+	var tags []string
+	for _, tag := range c.QueryParams()["tag"] {
+		if slug := sqlite.TagSlug(tag); slug != "" {
+			tags = append(tags, slug)
+		}
+	}
+	slices.Sort(tags)
+	tagMode := "any"
+	if len(tags) > 0 && c.QueryParam("tagMode") == "all" {
+		tagMode = "all"
+	}
+
+	posts, err := s.getPostCache.Get(sortOption{limit, offset, sort, strings.Join(slices.Compact(tags), ","), tagMode})
 	if err != nil {
 		log.Error("Failed to list posts", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list posts"})
@@ -37,6 +59,38 @@ func (s *Server) handleGetPosts(c echo.Context) error {
 	return c.JSON(http.StatusOK, posts)
 }
 
+// searchPosts answers GET /posts?q=. Results depend on the caller's roles, so
+// they bypass the shared list cache.
+func (s *Server) searchPosts(c echo.Context, q string, limit, offset int) error {
+	user := s.getEffectiveUser(c)
+	settings, _ := s.db.GetSettings()
+
+	opts := sqlite.SearchOptions{
+		Query:     q,
+		Limit:     limit,
+		Offset:    offset,
+		AllAccess: (settings != nil && settings.PublicAccess) || (user != nil && user.IsAdmin),
+	}
+	if user != nil {
+		opts.UserID = user.UserID
+		for _, r := range user.Roles {
+			if r != nil {
+				opts.RoleIDs = append(opts.RoleIDs, r.ID)
+			}
+		}
+	}
+
+	posts, err := s.db.SearchPosts(opts)
+	if err != nil {
+		log.Error("Failed to search posts", "query", q, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to search posts"})
+	}
+	if posts == nil {
+		posts = []*types.Post{}
+	}
+	return c.JSON(http.StatusOK, posts)
+}
+
 func (s *Server) handleGetPost(c echo.Context) error {
 	id := c.Param("id")
 	if id == "" {
@@ -49,6 +103,19 @@ func (s *Server) handleGetPost(c echo.Context) error {
 		log.Error("Failed to read post", "id", id, "error", err)
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
 	}
+	if c.QueryParam(shareParam) != "" {
+		return s.serveSharedPost(c, post)
+	}
+	if !post.Visible() {
+		if user := s.getEffectiveUser(c); user == nil || !user.IsAdmin {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+		}
+	}
+
+	post.Collections, err = s.db.PostCollections(post.ID)
+	if err != nil {
+		log.Warn("Failed to read post collections", "id", id, "error", err)
+	}
 
 	return c.JSON(http.StatusOK, post)
 }
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"drigo/pkg/types"
+)
+
+// grantRequest is the JSON body of PUT /posts/:id/grants/:userId.
+type grantRequest struct {
+	Effect    string     `json:"effect"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// handleGetGrants lists the per-user grants on a post.
+func (s *Server) handleGetGrants(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	post, err := s.db.ReadPostByExternalID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+	}
+	grants, err := s.db.PostGrants(post.ID)
+	if err != nil {
+		log.Error("Failed to list post grants", "id", post.PostKey, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list grants"})
+	}
+	if grants == nil {
+		grants = []types.PostGrant{}
+	}
+	return c.JSON(http.StatusOK, grants)
+}
+
+// handleSetGrant allows or denies one Discord user a post, replacing any
+// grant they already hold on it.
+func (s *Server) handleSetGrant(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	var req grantRequest
+	if err := c.Bind(&req); err != nil || (req.Effect != types.GrantAllow && req.Effect != types.GrantDeny) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": `effect must be "allow" or "deny"`})
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "expiresAt must be in the future"})
+	}
+	userID := strings.TrimSpace(c.Param("userId"))
+	if userID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Missing user id"})
+	}
+
+	post, err := s.db.ReadPostByExternalID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+	}
+	// Stored in UTC, since sqlite compares expiry times as text.
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		expiresAt = new(req.ExpiresAt.UTC())
+	}
+	grant := &types.PostGrant{
+		PostID:    post.ID,
+		UserID:    userID,
+		Effect:    req.Effect,
+		ExpiresAt: expiresAt,
+		GrantedBy: user.UserID,
+	}
+	if err := s.db.SetPostGrant(grant); err != nil {
+		log.Error("Failed to save post grant", "id", post.PostKey, "user", userID, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save grant"})
+	}
+	s.audit(c, types.AuditGrantSet, "grant", post.PostKey+":"+userID, types.AuditDiff(nil, map[string]any{
+		"effect":    grant.Effect,
+		"expiresAt": types.AuditTime(grant.ExpiresAt),
+	}), nil)
+	return c.JSON(http.StatusOK, grant)
+}
+
+// handleDeleteGrant removes a user's grant on a post.
+func (s *Server) handleDeleteGrant(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	post, err := s.db.ReadPostByExternalID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+	}
+	if err := s.db.DeletePostGrant(post.ID, c.Param("userId")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Grant not found"})
+		}
+		log.Error("Failed to delete post grant", "id", post.PostKey, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete grant"})
+	}
+	s.audit(c, types.AuditGrantDelete, "grant", post.PostKey+":"+c.Param("userId"), nil, nil)
+	return c.NoContent(http.StatusNoContent)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/labstack/echo/v4"
 	_ "golang.org/x/image/webp"
 
+	"drigo/pkg/access"
 	"drigo/pkg/exif"
 	"drigo/pkg/flight"
 	"drigo/pkg/types"
@@ -44,8 +45,11 @@ func (s *Server) handleGetImage(c echo.Context) error {
 		log.Error("Failed to find post for blob", "id", id, "error", err)
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Image not found"})
 	}
+	// Public galleries still hide scheduled posts until they go live, and
+	// archived ones.
+	publicAccess = publicAccess && post.Visible()
 
-	if !publicAccess && !canAccessPost(user, post) {
+	if !publicAccess && !s.canAccessPost(user, post) && !s.sharedMedia(c, post, uint(id)) {
 		if user == nil {
 			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
 		}
@@ -68,14 +72,15 @@ func (s *Server) handleGetImage(c echo.Context) error {
 
 		return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
 	}
+	s.recordDownload(user, post.ID)
 
-	blob, err := s.db.GetImageBlob(uint(id))
+	blob, err := s.db.GetImageBlobInfo(uint(id))
 	if err != nil {
 		log.Error("Failed to get image blob", "id", id, "error", err)
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Image not found"})
 	}
 
-	contentType := blob.GetContentType()
+	contentType := blob.ContentType
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
@@ -106,15 +111,25 @@ func (s *Server) handleGetImage(c echo.Context) error {
 		}
 	}
 
+	// PNGs are personalised with the viewer's EXIF, so their validator is per user.
+	// Answer revalidations before touching the cache or re-encoding.
+	var exifETag string
+	if user != nil && contentType == "image/png" {
+		exifETag = mediaETag(blob.Checksum, "exif", user.UserID)
+		c.Response().Header().Set("Cache-Control", "private, max-age=86400")
+		if notModified(c, exifETag, blob.CreatedAt) {
+			return nil
+		}
+	}
+
 	// Check cache first (only if we have a user to inject EXIF for)
 	if user != nil {
 		cacheKey := fmt.Sprintf("exif_%d_%s", id, user.UserID)
 		if cached, err := imageExifCache.Get(cacheKey); err == nil {
 			c.Response().Header().Set("Cache-Control", "private, max-age=86400")
-			c.Response().Header().Set("Content-Type", "image/png")
 			c.Response().Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, downloadName))
 			c.Response().Header().Set("X-Cache", "hit-memory")
-			return c.Stream(http.StatusOK, "image/png", bytes.NewReader(cached))
+			return serveBytes(c, "image/png", mediaETag(blob.Checksum, "exif", user.UserID), blob.CreatedAt, cached)
 		}
 	}
 
@@ -125,23 +140,20 @@ func (s *Server) handleGetImage(c echo.Context) error {
 			log.Error("Failed to generate EXIF", "error", err)
 			// Serve raw image with original headers on failure
 			c.Response().Header().Set("Cache-Control", "private, max-age=31536000")
-			c.Response().Header().Set("Content-Type", contentType)
 			c.Response().Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, filename))
-			return c.Stream(http.StatusOK, contentType, bytes.NewReader(blob.Data))
+			return s.serveBlob(c, blob, contentType)
 		}
 
 		c.Response().Header().Set("Cache-Control", "private, max-age=86400")
-		c.Response().Header().Set("Content-Type", "image/png")
 		c.Response().Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, downloadName))
 		c.Response().Header().Set("X-Cache", "generated-memory")
-		return c.Stream(http.StatusOK, "image/png", bytes.NewReader(exifData))
+		return serveBytes(c, "image/png", exifETag, blob.CreatedAt, exifData)
 	}
 
 	// For non-PNGs (including videos), serve raw content directly
 	c.Response().Header().Set("Cache-Control", "private, max-age=31536000")
-	c.Response().Header().Set("Content-Type", contentType)
 	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, filename))
-	return c.Stream(http.StatusOK, contentType, bytes.NewReader(blob.Data))
+	return s.serveBlob(c, blob, contentType)
 }
 
 func (s *Server) isImageExifCached(id uint, user *JwtCustomClaims) bool {
@@ -228,14 +240,23 @@ func (s *Server) handleGetBlur(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid image ID"})
 	}
 
+	info, err := s.db.GetImageBlobInfo(uint(id))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Image not found"})
+	}
+
+	etag := mediaETag(info.Checksum, "blur")
+	c.Response().Header().Set("Cache-Control", "public, max-age=31536000")
+	if notModified(c, etag, info.CreatedAt) {
+		return nil
+	}
+
 	cacheKey := fmt.Sprintf("blur_%d", id)
 
 	data, err := blurFlightCache.Get(cacheKey)
 	if err == nil && len(data) > 0 {
-		c.Response().Header().Set("Cache-Control", "public, max-age=31536000")
-		c.Response().Header().Set("Content-Type", "image/webp")
 		c.Response().Header().Set("X-Cache", "hit")
-		return c.Stream(http.StatusOK, "image/webp", bytes.NewReader(data))
+		return serveBytes(c, "image/webp", etag, info.CreatedAt, data)
 	}
 
 	blob, err := s.db.GetImageBlob(uint(id))
@@ -271,39 +292,46 @@ func (s *Server) handleGetBlur(c echo.Context) error {
 		_, _ = blurFlightCache.Force(cacheKey)
 	}()
 
-	c.Response().Header().Set("Cache-Control", "public, max-age=31536000")
-	c.Response().Header().Set("Content-Type", "image/webp")
 	c.Response().Header().Set("X-Cache", "generated")
-	return c.Stream(http.StatusOK, "image/webp", bytes.NewReader(result))
+	return serveBytes(c, "image/webp", etag, info.CreatedAt, result)
 }
 
-func canAccessPost(claims *JwtCustomClaims, p *types.Post) bool {
+func (s *Server) canAccessPost(claims *JwtCustomClaims, p *types.Post) bool {
 	if claims != nil && claims.IsAdmin {
 		return true
 	}
-	// If no roles required, public
-	if len(p.AllowedRoles) == 0 {
-		return true
-	}
-	if claims == nil {
+	// Drafts and scheduled posts stay hidden until they go live, archived
+	// posts until restored
+	if !p.Visible() {
 		return false
 	}
+	return access.Grant(s.activeGrant(claims, p), s.hasPostRole(claims, p))
+}
 
-	// Check intersection
-	userRoleMap := make(map[string]bool)
-	for _, r := range claims.Roles {
-		if r != nil {
-			userRoleMap[r.ID] = true
-		}
+// activeGrant returns the grant claims hold on p, or nil.
+func (s *Server) activeGrant(claims *JwtCustomClaims, p *types.Post) *types.PostGrant {
+	if claims == nil {
+		return nil
+	}
+	g, err := s.db.ActiveGrant(p.ID, claims.UserID, time.Now())
+	if err != nil {
+		log.Error("Failed to read post grant", "post", p.ID, "user", claims.UserID, "error", err)
 	}
+	return g
+}
 
-	for _, ar := range p.AllowedRoles {
-		if userRoleMap[ar.RoleID] {
-			return true
+// hasPostRole reports whether claims hold one of the roles or the tier p is
+// restricted to, ignoring admin rights and publication state.
+func (s *Server) hasPostRole(claims *JwtCustomClaims, p *types.Post) bool {
+	var roleIDs []string
+	if claims != nil {
+		for _, r := range claims.Roles {
+			if r != nil {
+				roleIDs = append(roleIDs, r.ID)
+			}
 		}
 	}
-
-	return false
+	return s.tiers().CanView(roleIDs, p)
 }
 
 // videoPreviewFlightCache coalesces concurrent video preview requests and reads from disk cache.
@@ -331,20 +359,26 @@ func (s *Server) handleGetThumb(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid image ID"})
 	}
 
-	// 1. Try to get existing thumbnail from DB
-	thumb, err := s.db.GetImageThumbnailByBlobID(uint(id))
-	if err == nil && len(thumb) > 0 {
-		// Detect content type of thumbnail
-		contentType := http.DetectContentType(thumb)
+	// 1. Try to get existing thumbnail from the blob store
+	if img, err := s.db.GetImageByBlobID(uint(id)); err == nil && img.ThumbnailKey != "" {
+		etag := mediaETag(img.ThumbnailChecksum)
 		c.Response().Header().Set("Cache-Control", "public, max-age=31536000")
-		c.Response().Header().Set("Content-Type", contentType)
-		// Trigger preload for full image EXIF if authorized (and if it's an image)
-		// We skip this for now or check if it's an image.
-		return c.Stream(http.StatusOK, contentType, bytes.NewReader(thumb))
+		if notModified(c, etag, img.CreatedAt) {
+			return nil
+		}
+		thumb, err := s.blobs.Get(c.Request().Context(), img.ThumbnailKey)
+		if err == nil && len(thumb) > 0 {
+			// Detect content type of thumbnail
+			contentType := http.DetectContentType(thumb)
+			// Trigger preload for full image EXIF if authorized (and if it's an image)
+			// We skip this for now or check if it's an image.
+			return serveBytes(c, contentType, etag, img.CreatedAt, thumb)
+		}
+		log.Warn("Failed to read thumbnail", "id", id, "key", img.ThumbnailKey, "error", err)
 	}
 
-	// 2. No thumbnail, fetch blob to check type and generate fallback
-	blob, err := s.db.GetImageBlob(uint(id))
+	// 2. No thumbnail, check the blob type to pick a fallback
+	blob, err := s.db.GetImageBlobInfo(uint(id))
 	if err != nil {
 		// Fallback to blur if no thumbnail and can't get blob?
 		// Actually if we can't get blob we can't do anything.
@@ -380,12 +414,9 @@ func (s *Server) serveVideoPreview(c echo.Context, id uint, blob *types.ImageBlo
 	publicAccess := settings != nil && settings.PublicAccess
 
 	// Check authorization
-	isAuthorized := publicAccess
-	if !isAuthorized && user != nil {
-		post, err := s.db.GetPostByBlobID(id)
-		if err == nil && canAccessPost(user, post) {
-			isAuthorized = true
-		}
+	isAuthorized := false
+	if post, err := s.db.GetPostByBlobID(id); err == nil {
+		isAuthorized = (publicAccess && post.Visible()) || (user != nil && s.canAccessPost(user, post))
 	}
 
 	// Cache key differentiation
@@ -395,13 +426,26 @@ func (s *Server) serveVideoPreview(c echo.Context, id uint, blob *types.ImageBlo
 	}
 	cacheKey := fmt.Sprintf("vid_prev_%d_%s", id, suffix)
 
+	etag := mediaETag(blob.Checksum, "preview", suffix)
+	c.Response().Header().Set("Cache-Control", "public, max-age=31536000")
+	if notModified(c, etag, blob.CreatedAt) {
+		return nil
+	}
+
 	// Check cache
 	data, err := videoPreviewFlightCache.Get(cacheKey)
 	if err == nil && len(data) > 0 {
-		c.Response().Header().Set("Cache-Control", "public, max-age=31536000")
-		c.Response().Header().Set("Content-Type", "image/gif")
 		c.Response().Header().Set("X-Cache", "hit")
-		return c.Stream(http.StatusOK, "image/gif", bytes.NewReader(data))
+		return serveBytes(c, "image/gif", etag, blob.CreatedAt, data)
+	}
+
+	// Generation needs the full video payload
+	if blob.Data == nil {
+		full, err := s.db.GetImageBlob(id)
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Image not found"})
+		}
+		blob = full
 	}
 
 	// Generate
@@ -435,8 +479,6 @@ func (s *Server) serveVideoPreview(c echo.Context, id uint, blob *types.ImageBlo
 		_, _ = videoPreviewFlightCache.Force(cacheKey)
 	}()
 
-	c.Response().Header().Set("Cache-Control", "public, max-age=31536000")
-	c.Response().Header().Set("Content-Type", "image/gif")
 	c.Response().Header().Set("X-Cache", "generated")
-	return c.Stream(http.StatusOK, "image/gif", bytes.NewReader(gifData))
+	return serveBytes(c, "image/gif", etag, blob.CreatedAt, gifData)
 }
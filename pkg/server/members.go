@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/charmbracelet/log"
+
+	"drigo/pkg/access"
+	"drigo/pkg/types"
+)
+
+// downloadKey identifies a download already recorded by this process.
+type downloadKey struct {
+	userID string
+	postID uint
+}
+
+// recordDownload remembers that user fetched the full media of a post. Each
+// pair is written once per run to keep image requests off the write lock.
+func (s *Server) recordDownload(user *JwtCustomClaims, postID uint) {
+	if user == nil || user.UserID == "" {
+		return
+	}
+	if _, seen := s.downloads.LoadOrStore(downloadKey{user.UserID, postID}, struct{}{}); seen {
+		return
+	}
+	if err := s.db.RecordDownload(user.UserID, postID); err != nil {
+		log.Warn("Failed to record download", "user", user.UserID, "post", postID, "error", err)
+	}
+}
+
+// watchMembers reconciles access when guild members change roles or leave.
+func (s *Server) watchMembers() {
+	session := s.bot.Session()
+	if session == nil {
+		return
+	}
+	session.AddHandler(func(_ *discordgo.Session, u *discordgo.GuildMemberUpdate) {
+		if u.Member == nil || u.User == nil || !s.isOwnGuild(u.GuildID) {
+			return
+		}
+		var before []string
+		if u.BeforeUpdate != nil {
+			before = u.BeforeUpdate.Roles
+		}
+		s.reconcileMember(u.User.ID, before, u.Roles, u.BeforeUpdate != nil)
+	})
+	session.AddHandler(func(_ *discordgo.Session, r *discordgo.GuildMemberRemove) {
+		if r.Member == nil || r.User == nil || !s.isOwnGuild(r.GuildID) {
+			return
+		}
+		var before []string
+		if r.BeforeDelete != nil {
+			before = r.BeforeDelete.Roles
+		}
+		s.reconcileMember(r.User.ID, before, nil, r.BeforeDelete != nil)
+	})
+}
+
+func (s *Server) isOwnGuild(guildID string) bool {
+	return s.config.GuildID == "" || guildID == s.config.GuildID
+}
+
+// reconcileMember drops the per-user caches of a member whose roles changed.
+// When roles were lost, their sessions stop trusting the JWT role snapshot and
+// every downloaded post they can no longer view is audited. after is nil once
+// the member has left; known is false when the previous roles weren't cached,
+// in which case any change is treated as a loss.
+func (s *Server) reconcileMember(userID string, before, after []string, known bool) {
+	left := after == nil
+	lost := slices.DeleteFunc(slices.Clone(before), func(id string) bool { return slices.Contains(after, id) })
+	if known && !left && len(lost) == 0 && len(before) == len(after) {
+		return
+	}
+
+	s.forgetUser(userID)
+	if known && !left && len(lost) == 0 {
+		return
+	}
+
+	now := time.Now()
+	if err := s.db.MarkRolesChanged(userID, now); err != nil {
+		log.Error("Failed to mark roles changed", "user", userID, "error", err)
+	}
+	log.Info("Member lost access", "user", userID, "left", left, "lostRoles", lost)
+	s.auditLostAccess(userID, before, after, lost, known, left)
+}
+
+// forgetUser drops everything cached or queued on behalf of a user.
+func (s *Server) forgetUser(userID string) {
+	suffix := "_" + userID
+	matches := func(key string) bool { return strings.HasSuffix(key, suffix) }
+	imageExifCache.DeleteFunc(matches)
+	resizeExifCache.DeleteFunc(matches)
+	s.preloadQueue.Forget(userID)
+}
+
+// lostAccess is the detail of an AuditAccessLost entry.
+type lostAccess struct {
+	UserID    string   `json:"userId"`
+	LostRoles []string `json:"lostRoles,omitempty"`
+	Left      bool     `json:"left,omitempty"`
+}
+
+// auditLostAccess writes an audit entry for each post the user downloaded
+// while their old roles allowed it and their new roles no longer do. If the old
+// roles are unknown, only leaving the guild is audited.
+func (s *Server) auditLostAccess(userID string, before, after, lost []string, known, left bool) {
+	if !known && !left {
+		return
+	}
+	if user, err := s.db.UserByID(userID); err == nil && user != nil && user.IsAdmin {
+		return
+	}
+	posts, err := s.db.DownloadedPosts(userID)
+	if err != nil {
+		log.Error("Failed to read downloads", "user", userID, "error", err)
+		return
+	}
+
+	detail, _ := json.Marshal(lostAccess{UserID: userID, LostRoles: lost, Left: left})
+	tiers := s.tiers()
+	now := time.Now()
+	for _, p := range posts {
+		if !p.Visible() {
+			continue
+		}
+		// Access is decided as canAccessPost does, so a grant, which applies
+		// whatever the roles, is never lost through them.
+		g, err := s.db.ActiveGrant(p.ID, userID, now)
+		if err != nil {
+			log.Error("Failed to read post grant", "post", p.ID, "user", userID, "error", err)
+		}
+		if (known && !access.Grant(g, tiers.CanView(before, p))) || access.Grant(g, tiers.CanView(after, p)) ||
+			(g != nil && g.Effect == types.GrantDeny) {
+			continue
+		}
+		if err := s.db.AddAuditEntry(&types.AuditEntry{
+			Action:     types.AuditAccessLost,
+			Source:     types.AuditSourceSystem,
+			TargetType: "post",
+			TargetID:   p.PostKey,
+			Detail:     string(detail),
+		}); err != nil {
+			log.Error("Failed to write audit entry", "user", userID, "post", p.PostKey, "error", err)
+		}
+	}
+}
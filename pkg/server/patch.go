@@ -1,7 +1,8 @@
 package server
 
 import (
-	"io"
+	"cmp"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -32,32 +33,34 @@ func (s *Server) handlePatchPost(c echo.Context) error {
 
 	var post *types.Post
 	if numericID, parseErr := strconv.ParseUint(idStr, 10, 32); parseErr == nil {
-		post, err = s.db.ReadPostWithBlobData(uint(numericID))
+		post, err = s.db.ReadPost(uint(numericID))
 		if err != nil {
-			post, err = s.db.ReadPostByExternalIDWithBlobData(idStr)
+			post, err = s.db.ReadPostByExternalID(idStr)
 		}
 	} else {
-		post, err = s.db.ReadPostByExternalIDWithBlobData(idStr)
+		post, err = s.db.ReadPostByExternalID(idStr)
 	}
 
 	if err != nil {
 		log.Error("Failed to find post for patch", "id", idStr, "error", err)
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
 	}
+	before := post.AuditFields()
 
-	// Parse multipart form (32MB max memory)
-	if err := c.Request().ParseMultipartForm(32 << 20); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to parse multipart form"})
+	upload, err := s.readUpload(c)
+	if err != nil {
+		return uploadErrorResponse(c, err)
 	}
+	defer upload.Cleanup()
 
 	// Read form values
-	title := c.FormValue("title")
-	description := c.FormValue("description")
-	rolesStr := c.FormValue("roles")
-	channelsStr := c.FormValue("channels")
-	removeImageIDsStr := c.FormValue("removeImageIds")
-	mediaOrderStr := c.FormValue("mediaOrder")
-	clearThumbnail := c.FormValue("clearThumbnail") == "1" || strings.EqualFold(c.FormValue("clearThumbnail"), "true")
+	title := upload.Value("title")
+	description := upload.Value("description")
+	rolesStr := upload.Value("roles")
+	channelsStr := upload.Value("channels")
+	removeImageIDsStr := upload.Value("removeImageIds")
+	mediaOrderStr := upload.Value("mediaOrder")
+	clearThumbnail := upload.Value("clearThumbnail") == "1" || strings.EqualFold(upload.Value("clearThumbnail"), "true")
 
 	// Update scalar fields on the post
 	post.Title = title
@@ -65,7 +68,7 @@ func (s *Server) handlePatchPost(c echo.Context) error {
 	post.ChannelID = channelsStr
 
 	// Use author-supplied date if provided
-	if dateStr := c.FormValue("postDate"); dateStr != "" {
+	if dateStr := upload.Value("postDate"); dateStr != "" {
 		if parsed, err := time.Parse(time.RFC3339, dateStr); err == nil {
 			post.Timestamp = parsed.UTC()
 		} else if parsed, err := time.Parse("2006-01-02", dateStr); err == nil {
@@ -74,22 +77,55 @@ func (s *Server) handlePatchPost(c echo.Context) error {
 	}
 
 	// Focus
-	if v, err := strconv.ParseFloat(c.FormValue("focusX"), 64); err == nil {
+	if v, err := strconv.ParseFloat(upload.Value("focusX"), 64); err == nil {
 		post.FocusX = &v
 	}
-	if v, err := strconv.ParseFloat(c.FormValue("focusY"), 64); err == nil {
+	if v, err := strconv.ParseFloat(upload.Value("focusY"), 64); err == nil {
 		post.FocusY = &v
 	}
 
 	// Handle Roles
 	post.AllowedRoles = s.parseAllowedRoles(rolesStr)
+	if upload.Has("minTier") {
+		minTier, ok := s.parseMinTier(upload.Value("minTier"))
+		if !ok {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid minTier"})
+		}
+		post.MinTierID = minTier
+	}
+	// Moving the early-access window lets it open, and be announced, again.
+	if upload.Has("publicAfter") {
+		publicAfter, ok := parseFormTime(upload.Value("publicAfter"))
+		if !ok {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid publicAfter"})
+		}
+		if !sameTime(post.PublicAfter, publicAfter) {
+			post.PublicAfter = publicAfter
+			post.WentPublicAt = nil
+		}
+	}
+	if upload.Has("announcePublic") {
+		post.AnnouncePublic, _ = strconv.ParseBool(upload.Value("announcePublic"))
+	}
+	// Archived posts keep their state here; they come back through restore.
+	if upload.Has("expiresAt") {
+		expiresAt, ok := parseFormTime(upload.Value("expiresAt"))
+		if !ok {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid expiresAt"})
+		}
+		post.ExpiresAt = expiresAt
+	}
+	if upload.Has("expireAnnouncements") {
+		post.ExpireAnnouncements, _ = strconv.ParseBool(upload.Value("expireAnnouncements"))
+	}
 
-	form := c.Request().MultipartForm
-	files := form.File["images"]
-	if len(files) == 0 {
-		files = form.File["image"]
+	// Tags are only replaced when the form sends the field.
+	if upload.Has("tags") {
+		post.Tags = parseTags(upload.List("tags"))
 	}
-	thumbFiles := form.File["thumbnail"]
+
+	files := upload.Files("images", "image")
+	thumbFiles := upload.Files("thumbnail")
 
 	removedImageIDs := make(map[uint]struct{})
 	for idToken := range strings.SplitSeq(removeImageIDsStr, ",") {
@@ -104,45 +140,56 @@ func (s *Server) handlePatchPost(c echo.Context) error {
 		removedImageIDs[uint(parsed)] = struct{}{}
 	}
 
+	// Every file must be stored, or "n:<idx>" tokens in mediaOrder would land
+	// on the wrong one.
 	newImages := make([]types.Image, 0, len(files))
-	for _, fileHeader := range files {
-		file, openErr := fileHeader.Open()
+	for _, file := range files {
+		imgBlob, openErr := upload.Blob(file)
 		if openErr != nil {
-			log.Error("Failed to open uploaded file", "filename", fileHeader.Filename, "error", openErr)
-			continue
-		}
-
-		imgBytes, readErr := io.ReadAll(file)
-		file.Close()
-		if readErr != nil {
-			log.Error("Failed to read uploaded file", "filename", fileHeader.Filename, "error", readErr)
-			continue
+			log.Error("Failed to open uploaded file", "filename", file.Filename, "error", openErr)
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Failed to read uploaded file %q", file.Filename)})
 		}
-
 		newImages = append(newImages, types.Image{
 			PostID: post.ID,
-			Blobs: []types.ImageBlob{
-				{
-					Index:       0,
-					Data:        imgBytes,
-					ContentType: fileHeader.Header.Get("Content-Type"),
-					Filename:    fileHeader.Filename,
-				},
-			},
+			Blobs:  []types.ImageBlob{imgBlob},
 		})
 	}
 
+	// Finished resumable uploads are placed with "u:<id>" tokens in mediaOrder.
+	resumed, uploadIDs, err := s.resolveUploads(upload, user.UserID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	resumedImages := make(map[string]types.Image, len(resumed))
+	for i, file := range resumed {
+		imgBlob, openErr := upload.Blob(file)
+		if openErr != nil {
+			log.Error("Failed to open upload", "id", uploadIDs[i], "error", openErr)
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Failed to read upload %q", uploadIDs[i])})
+		}
+		resumedImages[uploadIDs[i]] = types.Image{
+			PostID: post.ID,
+			Blobs:  []types.ImageBlob{imgBlob},
+		}
+	}
+
+	// Existing media is referenced by its stored checksum, so re-ordering
+	// never copies payload bytes.
 	copyImage := func(src types.Image) types.Image {
 		copied := types.Image{
-			Thumbnail: append([]byte(nil), src.Thumbnail...),
+			ThumbnailKey:      src.ThumbnailKey,
+			ThumbnailChecksum: src.ThumbnailChecksum,
+			ThumbnailBlurred:  src.ThumbnailBlurred,
 		}
 		copied.Blobs = make([]types.ImageBlob, 0, len(src.Blobs))
 		for i := range src.Blobs {
 			blob := src.Blobs[i]
 			copied.Blobs = append(copied.Blobs, types.ImageBlob{
 				Index:       blob.Index,
-				Data:        append([]byte(nil), blob.Data...),
+				StorageKey:  blob.StorageKey,
+				Checksum:    blob.Checksum,
 				ContentType: blob.ContentType,
+				Size:        blob.Size,
 				Filename:    blob.Filename,
 			})
 		}
@@ -157,6 +204,7 @@ func (s *Server) handlePatchPost(c echo.Context) error {
 	finalImages := make([]types.Image, 0, len(post.Images)+len(newImages))
 	usedExisting := make(map[uint]struct{})
 	usedNew := make(map[int]struct{})
+	usedUploads := make(map[string]struct{})
 
 	mediaOrderTokens := strings.Split(mediaOrderStr, ",")
 	if strings.TrimSpace(mediaOrderStr) != "" {
@@ -200,6 +248,19 @@ func (s *Server) handlePatchPost(c echo.Context) error {
 				finalImages = append(finalImages, newImages[idx])
 				usedNew[idx] = struct{}{}
 			}
+
+			if after, ok := strings.CutPrefix(token, "u:"); ok {
+				id := strings.TrimSpace(after)
+				img, ok := resumedImages[id]
+				if !ok {
+					continue
+				}
+				if _, alreadyUsed := usedUploads[id]; alreadyUsed {
+					continue
+				}
+				finalImages = append(finalImages, img)
+				usedUploads[id] = struct{}{}
+			}
 		}
 
 		for i := range post.Images {
@@ -218,6 +279,14 @@ func (s *Server) handlePatchPost(c echo.Context) error {
 			}
 			finalImages = append(finalImages, newImages[i])
 		}
+		for _, id := range uploadIDs {
+			if _, alreadyUsed := usedUploads[id]; alreadyUsed {
+				continue
+			}
+			if img, ok := resumedImages[id]; ok {
+				finalImages = append(finalImages, img)
+			}
+		}
 	} else {
 		for i := range post.Images {
 			img := post.Images[i]
@@ -227,6 +296,11 @@ func (s *Server) handlePatchPost(c echo.Context) error {
 			finalImages = append(finalImages, copyImage(img))
 		}
 		finalImages = append(finalImages, newImages...)
+		for _, id := range uploadIDs {
+			if img, ok := resumedImages[id]; ok {
+				finalImages = append(finalImages, img)
+			}
+		}
 	}
 
 	if len(finalImages) == 0 {
@@ -234,18 +308,19 @@ func (s *Server) handlePatchPost(c echo.Context) error {
 	}
 
 	if len(thumbFiles) > 0 {
-		thumbFile, openErr := thumbFiles[0].Open()
-		if openErr != nil {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to open thumbnail"})
-		}
-		thumbBytes, readErr := io.ReadAll(thumbFile)
-		thumbFile.Close()
+		thumbBytes, readErr := thumbFiles[0].ReadAll()
 		if readErr != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read thumbnail"})
 		}
 		finalImages[0].Thumbnail = thumbBytes
+		finalImages[0].ThumbnailKey = ""
+		finalImages[0].ThumbnailChecksum = ""
+		finalImages[0].ThumbnailBlurred = false
 	} else if clearThumbnail {
 		finalImages[0].Thumbnail = nil
+		finalImages[0].ThumbnailKey = ""
+		finalImages[0].ThumbnailChecksum = ""
+		finalImages[0].ThumbnailBlurred = false
 	}
 
 	post.Images = finalImages
@@ -256,12 +331,17 @@ func (s *Server) handlePatchPost(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update post"})
 	}
 	s.getPostCache.Reset()
+	upload.Cleanup()
+	s.finishUploads(uploadIDs)
+	s.wakeScheduler()
 
 	// Read again to return fully hydrated post
 	updated, err := s.db.ReadPost(post.ID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error reading updated post"})
 	}
+	s.refreshAnnouncements(updated, cmp.Or(s.config.PublicURL, getHost(c)))
+	s.audit(c, types.AuditPostUpdate, "post", updated.PostKey, types.AuditDiff(before, updated.AuditFields()), nil)
 
 	log.Info("Post updated", "id", idStr, "by", user.Username)
 	return c.JSON(http.StatusOK, updated)
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -9,11 +10,9 @@ import (
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
-	"io"
 	"net/http"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -27,7 +26,7 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 
-	"drigo/pkg/drigo"
+	"drigo/pkg/access"
 	"drigo/pkg/types"
 	"drigo/pkg/units"
 )
@@ -220,6 +219,10 @@ func (s *Server) handleCallback(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
 	}
 
+	login := auditEntry(c, types.AuditLogin, "user", user.UserID)
+	login.ActorID, login.ActorName = user.UserID, user.Username
+	s.writeAudit(login)
+
 	redirectTarget := fmt.Sprintf("/?token=%s", tokenString)
 	return c.Redirect(http.StatusTemporaryRedirect, redirectTarget)
 }
@@ -238,24 +241,29 @@ func (s *Server) handleCreatePost(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
 	}
 
-	// Parse multipart form (64MB max memory)
-	if err := c.Request().ParseMultipartForm(64 << 20); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to parse multipart form"})
+	// Files are spooled to disk and streamed into the blob store, so large
+	// videos never sit in memory.
+	upload, err := s.readUpload(c)
+	if err != nil {
+		return uploadErrorResponse(c, err)
 	}
+	defer upload.Cleanup()
 
-	title := c.FormValue("title")
-	description := c.FormValue("description")
-	rolesStr := c.FormValue("roles")
-	channelsStr := c.FormValue("channels")
-
-	form := c.Request().MultipartForm
-	files := form.File["images"] // Expecting "images" key for multiple files
-	thumbFiles := form.File["thumbnail"]
+	title := upload.Value("title")
+	description := upload.Value("description")
+	rolesStr := upload.Value("roles")
+	channelsStr := upload.Value("channels")
 
 	// Fallback to "image" if "images" is empty (backward compatibility)
-	if len(files) == 0 {
-		files = form.File["image"]
+	files := upload.Files("images", "image")
+	thumbFiles := upload.Files("thumbnail")
+
+	// Media finished through resumable uploads follows the form's own files.
+	resumed, uploadIDs, err := s.resolveUploads(upload, user.UserID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
+	files = append(files, resumed...)
 
 	if len(files) == 0 {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Missing images"})
@@ -264,8 +272,22 @@ func (s *Server) handleCreatePost(c echo.Context) error {
 	postKey := ksuid.New().String()
 	now := time.Now().UTC()
 
+	// Drafts stay hidden until published from the gallery and ignore publishAt.
+	draft, _ := strconv.ParseBool(upload.Value("draft"))
+
+	// Scheduled posts are dated to when they go live unless postDate says otherwise.
+	var publishAt *time.Time
+	if v := upload.Value("publishAt"); v != "" && !draft {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid publishAt"})
+		}
+		publishAt = new(parsed.UTC())
+		now = *publishAt
+	}
+
 	// Use author-supplied date if provided
-	if dateStr := c.FormValue("postDate"); dateStr != "" {
+	if dateStr := upload.Value("postDate"); dateStr != "" {
 		if parsed, err := time.Parse(time.RFC3339, dateStr); err == nil {
 			now = parsed.UTC()
 		} else if parsed, err := time.Parse("2006-01-02", dateStr); err == nil {
@@ -275,43 +297,46 @@ func (s *Server) handleCreatePost(c echo.Context) error {
 
 	// Handle Roles
 	allowedRoles := s.parseAllowedRoles(rolesStr)
+	minTier, ok := s.parseMinTier(upload.Value("minTier"))
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid minTier"})
+	}
+	publicAfter, ok := parseFormTime(upload.Value("publicAfter"))
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid publicAfter"})
+	}
+	announcePublic, _ := strconv.ParseBool(upload.Value("announcePublic"))
+	expiresAt, ok := parseFormTime(upload.Value("expiresAt"))
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid expiresAt"})
+	}
+	expireAnnouncements, _ := strconv.ParseBool(upload.Value("expireAnnouncements"))
+	// Generated thumbnails are blurred while the post is gated.
+	blur := access.Gated(&types.Post{AllowedRoles: allowedRoles, MinTierID: minTier, PublicAfter: publicAfter})
 
 	// Parse focus position (percentage 0-100, default 50 = center)
 	focusX := 50.0
-	if v, err := strconv.ParseFloat(c.FormValue("focusX"), 64); err == nil {
+	if v, err := strconv.ParseFloat(upload.Value("focusX"), 64); err == nil {
 		focusX = v
 	}
 	focusY := 50.0
-	if v, err := strconv.ParseFloat(c.FormValue("focusY"), 64); err == nil {
+	if v, err := strconv.ParseFloat(upload.Value("focusY"), 64); err == nil {
 		focusY = v
 	}
 
 	// Process Images
 	var postImages []types.Image
 
-	for _, fileHeader := range files {
-		file, err := fileHeader.Open()
-		if err != nil {
-			log.Error("Failed to open uploaded file", "filename", fileHeader.Filename, "error", err)
-			continue
-		}
-
-		imgBytes, err := io.ReadAll(file)
-		file.Close()
+	for _, file := range files {
+		// Every file must be stored, so postImages[0] stays files[0] for the
+		// thumbnail below.
+		imgBlob, err := upload.Blob(file)
 		if err != nil {
-			log.Error("Failed to read uploaded file", "filename", fileHeader.Filename, "error", err)
-			continue
+			log.Error("Failed to open uploaded file", "filename", file.Filename, "error", err)
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Failed to read uploaded file %q", file.Filename)})
 		}
-
 		postImages = append(postImages, types.Image{
-			Blobs: []types.ImageBlob{
-				{
-					Index:       0,
-					Data:        imgBytes,
-					ContentType: fileHeader.Header.Get("Content-Type"),
-					Filename:    fileHeader.Filename,
-				},
-			},
+			Blobs: []types.ImageBlob{imgBlob},
 		})
 	}
 
@@ -324,22 +349,24 @@ func (s *Server) handleCreatePost(c echo.Context) error {
 				return
 			}
 			postImages[0].Thumbnail = thumbBytes
+			postImages[0].ThumbnailBlurred = blur
 			finalS3ThumbURL = s3ThumbURL
 		}
 		if len(thumbFiles) > 0 {
-			thumbFile, err := thumbFiles[0].Open()
-			if err != nil {
-				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to open thumbnail"})
-			}
-			thumbBytes, err := io.ReadAll(thumbFile)
-			thumbFile.Close()
+			thumbBytes, err := thumbFiles[0].ReadAll()
 			if err != nil {
 				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read thumbnail"})
 			}
 			setThumbnail(thumbBytes, false, "Failed to process uploaded thumbnail")
 		}
-		if len(postImages[0].Thumbnail) == 0 && len(postImages[0].Blobs) > 0 {
-			setThumbnail(postImages[0].Blobs[0].Data, len(allowedRoles) > 0, "Failed to generate missing thumbnail")
+		// Only still images are decoded in memory to derive a thumbnail.
+		if len(postImages[0].Thumbnail) == 0 && files[0].IsImage() {
+			source, err := files[0].ReadAll()
+			if err != nil {
+				log.Error("Failed to read uploaded file", "filename", files[0].Filename, "error", err)
+			} else {
+				setThumbnail(source, blur, "Failed to generate missing thumbnail")
+			}
 		}
 	}
 
@@ -359,6 +386,16 @@ func (s *Server) handleCreatePost(c echo.Context) error {
 		FocusY:       &focusY,
 		Images:       postImages,
 		AllowedRoles: allowedRoles,
+		MinTierID:    minTier,
+		PublicAfter:  publicAfter,
+		Tags:         parseTags(upload.List("tags")),
+		PublishAt:    publishAt,
+		Draft:        draft,
+
+		AnnouncePublic: announcePublic,
+
+		ExpiresAt:           expiresAt,
+		ExpireAnnouncements: expireAnnouncements,
 	}
 
 	// Resolve Author from DB or Context
@@ -379,92 +416,22 @@ func (s *Server) handleCreatePost(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create post"})
 	}
 	s.getPostCache.Reset()
+	// Close the readers before dropping the resumable uploads they came from.
+	upload.Cleanup()
+	s.finishUploads(uploadIDs)
+	s.audit(c, types.AuditPostCreate, "post", postKey, types.AuditDiff(nil, post.AuditFields()), nil)
 
-	// Post to Discord Channels
-	channelIDs := strings.Split(channelsStr, ",")
-
-	// Prefer the processed thumbnail for Discord posts so restricted content stays preview-only.
-	firstFile := files[0]
-	thumbFilename := "thumb_" + firstFile.Filename + ".webp"
-	thumbURL := "attachment://" + thumbFilename
-	if finalS3ThumbURL != "" {
-		thumbURL = finalS3ThumbURL
-	}
-
-	embed := &discordgo.MessageEmbed{
-		Title:       title,
-		Type:        discordgo.EmbedTypeImage,
-		Timestamp:   now.Format(time.RFC3339),
-		Description: description,
-		Image: &discordgo.MessageEmbedImage{
-			URL: thumbURL,
-		},
-		Footer: &discordgo.MessageEmbedFooter{
-			Text: fmt.Sprintf(" +%d more", len(postImages)-1),
-		},
-	}
-	if len(postImages) <= 1 {
-		embed.Footer = nil
-	}
-
-	if embed.Description == "" {
-		embed.Description = "New post!"
-	}
-	if post.Author != nil {
-		embed.Author = &discordgo.MessageEmbedAuthor{
-			Name: post.Author.Username,
-		}
-	}
-
-	var sb strings.Builder
-	sb.WriteString("> Allowed roles:\n")
-	for _, r := range post.AllowedRoles {
-		sb.WriteString("> <@&")
-		sb.WriteString(r.RoleID)
-		sb.WriteString(">\n")
+	if post.Draft {
+		log.Info("Draft saved", "id", postKey)
+		return c.JSON(http.StatusOK, post)
 	}
-
-	messageComponents := append([]discordgo.MessageComponent{
-		discordgo.Button{
-			Label: "View in Gallery",
-			Style: discordgo.LinkButton,
-			URL:   fmt.Sprintf("%s/post/%s", getHost(c), postKey),
-		},
-	}, drigo.BuildShowActionsRow(postKey).Components...)
-
-	components := []discordgo.MessageComponent{
-		discordgo.ActionsRow{
-			Components: messageComponents,
-		},
+	if post.PublishedAt == nil {
+		log.Info("Post scheduled", "id", postKey, "publishAt", post.PublishAt)
+		s.wakeScheduler()
+		return c.JSON(http.StatusOK, post)
 	}
 
-	for _, chID := range channelIDs {
-		chID = strings.TrimSpace(chID)
-		if chID == "" {
-			continue
-		}
-
-		var discordFiles []*discordgo.File
-		if len(postImages[0].Thumbnail) > 0 && finalS3ThumbURL == "" {
-			discordFiles = append(discordFiles, &discordgo.File{
-				Name:        thumbFilename,
-				ContentType: "image/webp",
-				Reader:      bytes.NewReader(postImages[0].Thumbnail),
-			})
-		}
-
-		_, err := s.bot.Session().ChannelMessageSendComplex(chID, &discordgo.MessageSend{
-			Content:    sb.String(),
-			Embeds:     []*discordgo.MessageEmbed{embed},
-			Components: components,
-			Files:      discordFiles,
-		})
-		if err != nil {
-			log.Error("Failed to send to channel", "channel", chID, "error", err)
-		} else {
-			log.Info("Posted to Discord channel", "channel", chID)
-		}
-	}
+	s.announcePost(post, postImages[0].Thumbnail, finalS3ThumbURL, cmp.Or(s.config.PublicURL, getHost(c)))
 
 	return c.JSON(http.StatusOK, post)
 }
@@ -2,18 +2,23 @@ package server
 
 import (
 	"runtime"
+	"sync"
+	"time"
 
 	"github.com/charmbracelet/log"
 )
 
 type PreloadJob struct {
-	ImageID uint
-	User    *JwtCustomClaims
+	ImageID  uint
+	User     *JwtCustomClaims
+	QueuedAt time.Time
 }
 
 type PreloadQueue struct {
 	jobs   chan PreloadJob
 	server *Server
+	// forgotten maps a user ID to when their queued jobs were invalidated.
+	forgotten sync.Map
 }
 
 func NewPreloadQueue(server *Server) *PreloadQueue {
@@ -36,6 +41,10 @@ func (q *PreloadQueue) StartWorkers() {
 
 func (q *PreloadQueue) worker() {
 	for job := range q.jobs {
+		if at, ok := q.forgotten.Load(job.User.UserID); ok && !job.QueuedAt.After(at.(time.Time)) {
+			continue
+		}
+
 		// Prioritize check: Check if already cached
 		if q.server.isImageExifCached(job.ImageID, job.User) {
 			continue
@@ -57,10 +66,15 @@ func (q *PreloadQueue) Enqueue(imageID uint, user *JwtCustomClaims) {
 	}
 
 	select {
-	case q.jobs <- PreloadJob{ImageID: imageID, User: user}:
+	case q.jobs <- PreloadJob{ImageID: imageID, User: user, QueuedAt: time.Now()}:
 		// Queued
 	default:
 		// Queue full, drop request (standard pattern for shedding load in preloading)
 		log.Debug("Preload queue full, dropping job", "id", imageID)
 	}
 }
+
+// Forget drops the jobs queued so far for a user, e.g. after their roles changed.
+func (q *PreloadQueue) Forget(userID string) {
+	q.forgotten.Store(userID, time.Now())
+}
@@ -130,40 +130,62 @@ func (s *Server) handleGetResizedImage(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Image not found"})
 	}
-	if !publicAccess && !canAccessPost(user, post) {
+	publicAccess = publicAccess && post.Visible()
+	if !publicAccess && !s.canAccessPost(user, post) && !s.sharedMedia(c, post, uint(id)) {
 		return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
 	}
 
-	blob, err := s.db.GetImageBlob(uint(id))
+	info, err := s.db.GetImageBlobInfo(uint(id))
 	if err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Image not found"})
 	}
 
-	// If video, swap blob data with thumbnail or generated preview
-	if strings.HasPrefix(blob.GetContentType(), "video/") {
-		thumb, err := s.db.GetImageThumbnailByBlobID(uint(id))
-		if err == nil && len(thumb) > 0 {
-			// Use existing thumbnail
-			blob.Data = thumb
-			blob.ContentType = http.DetectContentType(thumb) // Likely image/jpeg or image/webp
-		} else {
-			// Generate a WebM resize from the video
-			resizedVideo, err := video.ResizeToWebM(blob.Data, targetWidth)
-			if err != nil {
-				log.Error("Failed to resize video to WebM", "id", id, "error", err)
-				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to process video"})
-			}
-			blob.Data = resizedVideo
-			blob.ContentType = "video/webm"
-
-			// We effectively have the result now, but the logic below expects to resize 'blob.Data'
-			// which is now already resized WebM.
-			// To avoid double-processing (and failure since resizeStatic/resizeAnimatedGIF won't handle WebM),
-			// we can just cache and return here, OR we can Structure the code to handle this 'already processed' case.
-			// Let's optimize:
-			// If we are here, we have the final result.
-			// We should probably just return it / cache it immediately.
-			// However, to keep flow consistent, let's flag it.
+	// The output is fully determined by the source checksum, the requested size
+	// and quality, and (for EXIF-tagged output) the viewer. Videos are resized
+	// from their thumbnail when one exists, so that takes part too.
+	variant := []string{"w" + wStr, "q" + strconv.Itoa(quality)}
+	if isPercentage {
+		variant[0] = "p" + pStr
+	}
+	if strings.HasPrefix(info.ContentType, "video/") {
+		if img, err := s.db.GetImageByBlobID(uint(id)); err == nil && img.ThumbnailChecksum != "" {
+			variant = append(variant, "t"+img.ThumbnailChecksum)
+		}
+	}
+	if user != nil {
+		variant = append(variant, "u"+user.UserID)
+	}
+	etag := mediaETag(info.Checksum, variant...)
+	c.Response().Header().Set("Cache-Control", "private, max-age=86400")
+	if notModified(c, etag, info.CreatedAt) {
+		return nil
+	}
+
+	// Percentage sizes depend on the source dimensions, so they are cached
+	// under the percentage rather than the width it works out to.
+	cacheKey := fmt.Sprintf("%d_%d_q%d", id, targetWidth, quality)
+	if isPercentage {
+		cacheKey = fmt.Sprintf("%d_p%d_q%d", id, targetWidth, quality)
+	}
+
+	entry, err := resizeFlightCache.Get(cacheKey)
+	if err == nil && len(entry.Data) > 0 {
+		c.Response().Header().Set("X-Cache", "hit")
+		return serveBytes(c, entry.ContentType, etag, info.CreatedAt, entry.Data)
+	}
+
+	// Videos are resized from their thumbnail when they have one, so the
+	// original is only read when it has to be decoded.
+	var blob *types.ImageBlob
+	if strings.HasPrefix(info.ContentType, "video/") {
+		if thumb, err := s.db.GetImageThumbnailByBlobID(uint(id)); err == nil && len(thumb) > 0 {
+			blob = &types.ImageBlob{Data: thumb, ContentType: http.DetectContentType(thumb)}
+		}
+	}
+	if blob == nil {
+		blob, err = s.db.GetImageBlob(uint(id))
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Image not found"})
 		}
 	}
 
@@ -176,14 +198,15 @@ func (s *Server) handleGetResizedImage(c echo.Context) error {
 		targetWidth = max(cfg.Width*targetWidth/100, 1)
 	}
 
-	cacheKey := fmt.Sprintf("%d_%d_q%d", id, targetWidth, quality)
-
-	entry, err := resizeFlightCache.Get(cacheKey)
-	if err == nil && len(entry.Data) > 0 {
-		c.Response().Header().Set("Cache-Control", "private, max-age=86400")
-		c.Response().Header().Set("Content-Type", entry.ContentType)
-		c.Response().Header().Set("X-Cache", "hit")
-		return c.Stream(http.StatusOK, entry.ContentType, bytes.NewReader(entry.Data))
+	// A video without a thumbnail is resized to WebM as a whole.
+	if strings.HasPrefix(blob.GetContentType(), "video/") {
+		resizedVideo, err := video.ResizeToWebM(blob.Data, targetWidth)
+		if err != nil {
+			log.Error("Failed to resize video to WebM", "id", id, "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to process video"})
+		}
+		blob.Data = resizedVideo
+		blob.ContentType = "video/webm"
 	}
 
 	isAnimatedGIF := false
@@ -239,11 +262,9 @@ func (s *Server) handleGetResizedImage(c echo.Context) error {
 	if user != nil && contentType != "image/gif" && contentType != "video/webm" {
 		exifCacheKey := fmt.Sprintf("resize_exif_%s_%s", cacheKey, user.UserID)
 		if cached, err := resizeExifCache.Get(exifCacheKey); err == nil {
-			c.Response().Header().Set("Cache-Control", "private, max-age=86400")
-			c.Response().Header().Set("Content-Type", "image/png")
 			c.Response().Header().Set("Content-Disposition", "inline; filename=\"resized.png\"")
 			c.Response().Header().Set("X-Cache", "hit-memory")
-			return c.Stream(http.StatusOK, "image/png", bytes.NewReader(cached))
+			return serveBytes(c, "image/png", etag, info.CreatedAt, cached)
 		}
 
 		// Not in cache, convert WebP result to PNG with EXIF
@@ -271,19 +292,15 @@ func (s *Server) handleGetResizedImage(c echo.Context) error {
 				exifData := buf.Bytes()
 				resizeExifCache.Set(exifCacheKey, exifData)
 
-				c.Response().Header().Set("Cache-Control", "private, max-age=86400")
-				c.Response().Header().Set("Content-Type", "image/png")
 				c.Response().Header().Set("Content-Disposition", "inline; filename=\"resized.png\"")
 				c.Response().Header().Set("X-Cache", "generated-memory")
-				return c.Stream(http.StatusOK, "image/png", bytes.NewReader(exifData))
+				return serveBytes(c, "image/png", etag, info.CreatedAt, exifData)
 			}
 		}
 	}
 
-	c.Response().Header().Set("Cache-Control", "private, max-age=86400")
-	c.Response().Header().Set("Content-Type", contentType)
 	c.Response().Header().Set("X-Cache", "generated")
-	return c.Stream(http.StatusOK, contentType, bytes.NewReader(result))
+	return serveBytes(c, contentType, etag, info.CreatedAt, result)
 }
 
 // resizeExifCache stores resized image data with EXIF metadata for specific users.
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"drigo/pkg/sqlite"
+	"drigo/pkg/types"
+)
+
+// schedulerInterval bounds how long the scheduler sleeps, so a missed wake-up
+// only delays a publication rather than losing it.
+const schedulerInterval = time.Minute
+
+// wakeScheduler makes the scheduler re-read the next publication time.
+func (s *Server) wakeScheduler() {
+	select {
+	case s.scheduleWake <- struct{}{}:
+	default:
+	}
+}
+
+// runScheduler publishes scheduled posts as they come due, opens posts whose
+// early-access window has closed and archives expired ones. Schedules live in the database, so
+// posts that came due while the server was down go out as soon as it starts.
+func (s *Server) runScheduler(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-s.scheduleWake:
+		}
+		s.publishDuePosts()
+		s.openDuePosts()
+		s.archiveDuePosts()
+		timer.Reset(s.nextScheduleCheck())
+	}
+}
+
+// nextScheduleCheck returns how long to sleep until the next scheduled post,
+// early-access window or expiry.
+func (s *Server) nextScheduleCheck() time.Duration {
+	wait := schedulerInterval
+	for _, next := range []struct {
+		what string
+		at   func() (*time.Time, error)
+	}{
+		{"scheduled post", s.db.NextPublishAt},
+		{"early-access window", s.db.NextPublicAfter},
+		{"expiry", s.db.NextExpiresAt},
+	} {
+		at, err := next.at()
+		if err != nil {
+			log.Error("Failed to read next "+next.what, "error", err)
+		} else if at != nil {
+			wait = min(wait, time.Until(*at))
+		}
+	}
+	return max(wait, 0)
+}
+
+// publishDuePosts publishes every post that has come due and announces it.
+// Posts are marked published before announcing, so a crash in between skips
+// the announcement rather than repeating it.
+func (s *Server) publishDuePosts() {
+	posts, err := s.db.PublishDuePosts(time.Now())
+	if err != nil {
+		log.Error("Failed to publish scheduled posts", "error", err)
+		return
+	}
+	if len(posts) == 0 {
+		return
+	}
+	s.getPostCache.Reset()
+	for _, post := range posts {
+		log.Info("Publishing scheduled post", "id", post.PostKey)
+		thumb, thumbURL := s.postThumbnail(post)
+		s.announcePost(post, thumb, thumbURL, s.config.PublicURL)
+	}
+}
+
+// openDuePosts makes posts whose early-access window has closed public: their
+// blurred thumbnail is replaced with a clear one, their announcements are
+// updated, and those asking for it are announced again.
+func (s *Server) openDuePosts() {
+	posts, err := s.db.OpenDuePosts(time.Now())
+	if err != nil {
+		log.Error("Failed to open early-access posts", "error", err)
+		return
+	}
+	if len(posts) == 0 {
+		return
+	}
+	for _, post := range posts {
+		log.Info("Early access ended", "id", post.PostKey)
+		post = s.revealThumbnail(post)
+		s.refreshAnnouncements(post, s.config.PublicURL)
+		if post.AnnouncePublic {
+			thumb, thumbURL := s.postThumbnail(post)
+			s.announcePost(post, thumb, thumbURL, s.config.PublicURL)
+		}
+	}
+	// Reset once the thumbnails are clear, so listings never cache the
+	// blurred ones.
+	s.getPostCache.Reset()
+}
+
+// revealThumbnail replaces a thumbnail that was blurred because the post was
+// gated with a clear one made from its first image, and returns the post as
+// stored afterwards.
+func (s *Server) revealThumbnail(post *types.Post) *types.Post {
+	if len(post.Images) == 0 || !post.Images[0].ThumbnailBlurred || len(post.Images[0].Blobs) == 0 {
+		return post
+	}
+	source, err := s.db.GetImageBlob(post.Images[0].Blobs[0].ID)
+	if err != nil || !strings.HasPrefix(source.GetContentType(), "image/") {
+		return post
+	}
+	thumb, _, err := s.processThumbnail(s.ctx, source.Data, false, post.PostKey)
+	if err != nil {
+		log.Error("Failed to regenerate thumbnail", "id", post.PostKey, "error", err)
+		return post
+	}
+
+	if err := s.db.SetImageThumbnail(post.Images[0].ID, thumb, false); err != nil {
+		log.Error("Failed to save clear thumbnail", "id", post.PostKey, "error", err)
+		return post
+	}
+	if updated, err := s.db.ReadPost(post.ID); err == nil {
+		return updated
+	}
+	return post
+}
+
+// scheduleRequest is the JSON body of PUT /schedule/:id.
+type scheduleRequest struct {
+	PublishAt time.Time `json:"publishAt"`
+}
+
+// handleGetSchedule lists scheduled posts and drafts.
+func (s *Server) handleGetSchedule(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	posts, err := s.db.UnpublishedPosts()
+	if err != nil {
+		log.Error("Failed to list scheduled posts", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list scheduled posts"})
+	}
+	if posts == nil {
+		posts = []*types.Post{}
+	}
+	return c.JSON(http.StatusOK, posts)
+}
+
+// handleSchedulePost sets or moves the publication time of an unpublished
+// post. Scheduling a draft takes it out of drafts.
+func (s *Server) handleSchedulePost(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	var req scheduleRequest
+	if err := c.Bind(&req); err != nil || req.PublishAt.IsZero() {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid publishAt"})
+	}
+	return s.setSchedule(c, &req.PublishAt)
+}
+
+// handleCancelSchedule clears the publication time of an unpublished post and
+// returns it to drafts.
+func (s *Server) handleCancelSchedule(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+	return s.setSchedule(c, nil)
+}
+
+func (s *Server) setSchedule(c echo.Context, at *time.Time) error {
+	idStr := c.Param("id")
+	post, err := s.db.ReadPostByExternalID(idStr)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+	}
+
+	before := post.AuditFields()
+	if err := s.db.SchedulePost(post.ID, at); err != nil {
+		switch {
+		case errors.Is(err, sqlite.ErrAlreadyPublished):
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Post is already published"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+		}
+		log.Error("Failed to schedule post", "id", idStr, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to schedule post"})
+	}
+	s.wakeScheduler()
+
+	post, err = s.db.ReadPostByExternalID(idStr)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+	}
+	action := types.AuditPostSchedule
+	if at == nil {
+		action = types.AuditPostUnschedule
+	}
+	s.audit(c, action, "post", post.PostKey, types.AuditDiff(before, post.AuditFields()), nil)
+	return c.JSON(http.StatusOK, post)
+}
+
+// sameTime reports whether two optional times are equal.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// parseFormTime reads an optional time form field such as publicAfter or
+// expiresAt: an RFC 3339 time, or empty for none. ok is false if the value
+// doesn't parse.
+func parseFormTime(value string) (at *time.Time, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, false
+	}
+	return new(parsed.UTC()), true
+}
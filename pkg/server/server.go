@@ -7,7 +7,9 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -17,6 +19,8 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 
 	"drigo/app"
+	"drigo/pkg/access"
+	"drigo/pkg/blob"
 	"drigo/pkg/bucket"
 	"drigo/pkg/discord"
 	"drigo/pkg/flight"
@@ -42,7 +46,12 @@ type Server struct {
 	preloadQueue *PreloadQueue
 	getPostCache flight.Cache[sortOption, []*types.Post]
 	guildCache   flight.Cache[struct{}, *GuildData]
+	tierCache    flight.Cache[struct{}, access.Tiers]
 	bucket       bucket.Uploader
+	blobs        blob.Store
+	uploadLocks  sync.Map // tus upload ID -> struct{} while a request holds it
+	scheduleWake chan struct{}
+	downloads    sync.Map // downloadKey -> struct{} once recorded this run
 }
 
 type Config struct {
@@ -54,6 +63,20 @@ type Config struct {
 	DB           sqlite.DB
 	Bot          discord.Bot
 	Bucket       bucket.Uploader
+	Blobs        blob.Store
+
+	// MaxUploadSize caps a whole multipart request and MaxFileSize each file
+	// within it, in bytes. Zero means unlimited.
+	MaxUploadSize int64
+	MaxFileSize   int64
+	// UploadDir holds in-progress resumable uploads.
+	UploadDir string
+	// PublicURL is the gallery's external base URL, used for links in posts
+	// sent outside a request such as scheduled publications.
+	PublicURL string
+	// TrashRetention is how long deleted posts and replaced media stay in
+	// the trash before they are purged. Zero keeps them until purged by hand.
+	TrashRetention time.Duration
 }
 
 func New(cfg *Config) *Server {
@@ -63,16 +86,32 @@ func New(cfg *Config) *Server {
 	if cfg.Bot == nil {
 		log.Fatal("Bot is nil")
 	}
+	if cfg.Blobs == nil {
+		log.Fatal("Blob store is nil")
+	}
+
+	if cfg.UploadDir == "" {
+		cfg.UploadDir = filepath.Join(os.TempDir(), "aegis-uploads")
+	}
+	if err := os.MkdirAll(cfg.UploadDir, 0o755); err != nil {
+		log.Fatal("Failed to create upload directory", "path", cfg.UploadDir, "error", err)
+	}
 
 	e := echo.New()
 
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		// Plain OPTIONS on /uploads is tus capability discovery, not a preflight.
+		Skipper: func(c echo.Context) bool {
+			req := c.Request()
+			return req.Method == http.MethodOptions && req.Header.Get(echo.HeaderAccessControlRequestMethod) == "" &&
+				strings.HasPrefix(req.URL.Path, "/uploads")
+		},
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{echo.GET, echo.POST, echo.PUT, echo.DELETE, echo.OPTIONS, echo.PATCH},
-		AllowHeaders:     []string{echo.HeaderContentType, echo.HeaderAuthorization, "X-CSRF-Token"},
-		ExposeHeaders:    []string{"Link"},
+		AllowMethods:     []string{echo.GET, echo.HEAD, echo.POST, echo.PUT, echo.DELETE, echo.OPTIONS, echo.PATCH},
+		AllowHeaders:     append([]string{echo.HeaderContentType, echo.HeaderAuthorization, "X-CSRF-Token", "Range", "If-None-Match", echo.HeaderIfModifiedSince}, tusHeaders...),
+		ExposeHeaders:    append([]string{"Link", "ETag", "Content-Range", "Accept-Ranges", echo.HeaderLocation}, tusHeaders...),
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -104,8 +143,15 @@ func New(cfg *Config) *Server {
 		ctx:    cfg.Context,
 		cancel: cfg.Cancel,
 		bucket: cfg.Bucket,
+		blobs:  cfg.Blobs,
 		getPostCache: flight.NewCache(func(option sortOption) ([]*types.Post, error) {
-			return cfg.DB.ListPosts(option.limit, option.offset, option.sort)
+			return cfg.DB.ListPosts(sqlite.ListOptions{
+				Limit:   option.limit,
+				Offset:  option.offset,
+				Sort:    option.sort,
+				Tags:    strings.Split(option.tags, ","),
+				TagMode: option.tagMode,
+			})
 		}),
 		guildCache: flight.NewCache(func(_ struct{}) (*GuildData, error) {
 			roles, _ := cfg.DB.GetCachedRoles()
@@ -122,9 +168,20 @@ func New(cfg *Config) *Server {
 				Name:     guildName,
 			}, nil
 		}),
+		tierCache: flight.NewCache(func(_ struct{}) (access.Tiers, error) {
+			tiers, err := cfg.DB.ListTiers()
+			if err != nil {
+				return access.Tiers{}, err
+			}
+			return access.NewTiers(tiers), nil
+		}),
+		scheduleWake: make(chan struct{}, 1),
 	}
 
+	cfg.Bot.OnPostChanged(s.discordPostChanged)
+
 	s.preloadQueue = NewPreloadQueue(s)
+	s.watchMembers()
 
 	s.routes()
 
@@ -155,6 +212,10 @@ func (s *Server) Run() error {
 		serverErrCh <- nil
 	}()
 
+	go s.runUploadJanitor(ctx)
+	go s.runTrashPurge(ctx)
+	go s.runScheduler(ctx)
+
 	botErrCh := make(chan error, 1)
 	go func() {
 		botErrCh <- s.bot.Start()
@@ -269,9 +330,63 @@ func (s *Server) routes() {
 
 	s.router.POST("/posts", s.handleCreatePost)
 	s.router.POST("/posts/:id/dm", s.handlePostDM)
+	s.router.POST("/posts/:id/publish", s.handlePublishPost)
+	s.router.GET("/posts/:id/preview", s.handlePreviewPost)
 	s.router.PATCH("/posts/:id", s.handlePatchPost)
 	s.router.DELETE("/posts/:id", s.handleDeletePost)
 
+	// Per-user grants
+	s.router.GET("/posts/:id/grants", s.handleGetGrants)
+	s.router.PUT("/posts/:id/grants/:userId", s.handleSetGrant)
+	s.router.DELETE("/posts/:id/grants/:userId", s.handleDeleteGrant)
+
+	// Share links
+	s.router.GET("/posts/:id/shares", s.handleGetShares)
+	s.router.POST("/posts/:id/shares", s.handleCreateShare)
+	s.router.DELETE("/shares/:id", s.handleRevokeShare)
+
+	// Resumable uploads (tus)
+	uploads := s.router.Group("/uploads", tusMiddleware)
+	uploads.OPTIONS("", s.handleUploadOptions)
+	uploads.POST("", s.handleCreateUpload)
+	uploads.HEAD("/:id", s.handleHeadUpload)
+	uploads.PATCH("/:id", s.handlePatchUpload)
+	uploads.DELETE("/:id", s.handleDeleteUpload)
+
+	// Tags
+	s.router.GET("/tags", s.handleGetTags)
+	s.router.POST("/tags", s.handleCreateTag)
+	s.router.PATCH("/tags/:id", s.handleRenameTag)
+	s.router.DELETE("/tags/:id", s.handleDeleteTag)
+
+	// Tiers
+	s.router.GET("/tiers", s.handleGetTiers)
+	s.router.PUT("/tiers", s.handleSetTiers)
+
+	// Scheduled publishing
+	s.router.GET("/schedule", s.handleGetSchedule)
+	s.router.PUT("/schedule/:id", s.handleSchedulePost)
+	s.router.DELETE("/schedule/:id", s.handleCancelSchedule)
+
+	// Expired posts
+	s.router.GET("/archive", s.handleGetArchive)
+	s.router.POST("/archive/:id/restore", s.handleRestorePost)
+
+	// Trash
+	s.router.GET("/trash", s.handleGetTrash)
+	s.router.POST("/trash/posts/:id/restore", s.handleRestoreTrashedPost)
+	s.router.DELETE("/trash/posts/:id", s.handlePurgeTrashedPost)
+	s.router.POST("/trash/media/:id/restore", s.handleRestoreTrashedMedia)
+	s.router.DELETE("/trash/media/:id", s.handlePurgeTrashedMedia)
+
+	// Collections
+	s.router.GET("/collections", s.handleGetCollections)
+	s.router.GET("/collections/:id", s.handleGetCollection)
+	s.router.POST("/collections", s.handleCreateCollection)
+	s.router.PATCH("/collections/:id", s.handlePatchCollection)
+	s.router.PUT("/collections/:id/posts", s.handleSetCollectionPosts)
+	s.router.DELETE("/collections/:id", s.handleDeleteCollection)
+
 	// Settings
 	s.router.GET("/settings", s.handleGetSettings)
 	s.router.POST("/settings", s.handleUpdateSettings)
@@ -280,6 +395,9 @@ func (s *Server) routes() {
 	s.router.GET("/users", s.handleGetUsers)
 	s.router.POST("/users/:id/admin", s.handleToggleAdmin)
 
+	// Audit log
+	s.router.GET("/audit", s.handleGetAudit)
+
 	staticFS := app.FS()
 	staticFSWrapper, err := fs.Sub(staticFS, ".")
 	if err != nil {
@@ -295,7 +413,10 @@ func (s *Server) routes() {
 			strings.HasPrefix(path, "/images/") || strings.HasPrefix(path, "/thumb/") ||
 			strings.HasPrefix(path, "/blur/") || strings.HasPrefix(path, "/login") ||
 			strings.HasPrefix(path, "/auth/") || strings.HasPrefix(path, "/upload") ||
-			strings.HasPrefix(path, "/roles") {
+			strings.HasPrefix(path, "/roles") || strings.HasPrefix(path, "/tags") || strings.HasPrefix(path, "/tiers") ||
+			strings.HasPrefix(path, "/collections") || strings.HasPrefix(path, "/schedule") ||
+			strings.HasPrefix(path, "/shares") || strings.HasPrefix(path, "/archive") ||
+			strings.HasPrefix(path, "/trash") || strings.HasPrefix(path, "/audit") {
 			return c.NoContent(http.StatusNotFound)
 		}
 
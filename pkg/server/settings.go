@@ -43,10 +43,15 @@ func (s *Server) handleUpdateSettings(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
 	}
 
+	before, err := s.db.GetSettings()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update settings"})
+	}
 	updated, err := s.db.UpdateSettings(newSettings)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update settings"})
 	}
+	s.audit(c, types.AuditSettings, "settings", "", types.AuditDiff(before.AuditFields(), updated.AuditFields()), nil)
 
 	return c.JSON(http.StatusOK, updated)
 }
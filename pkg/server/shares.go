@@ -0,0 +1,252 @@
+package server
+
+import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"drigo/pkg/types"
+)
+
+// shareParam is the query parameter carrying a share token in place of a JWT.
+const shareParam = "share"
+
+// shareRequest is the JSON body of POST /posts/:id/shares.
+type shareRequest struct {
+	ExpiresAt time.Time `json:"expiresAt"`
+	MaxUses   int       `json:"maxUses"`
+	Media     []int     `json:"media"`
+}
+
+// shareResponse is a share link with the gallery URL that opens it.
+type shareResponse struct {
+	types.ShareLink
+	URL string `json:"url"`
+}
+
+// shareUse is the detail of an AuditShareUse entry.
+type shareUse struct {
+	LinkID uint `json:"linkId"`
+}
+
+// newShareToken returns a random token signed for postKey, so tokens for one
+// post can't be replayed against another and forged ones never reach the
+// database.
+func newShareToken(postKey string) (string, error) {
+	nonce := make([]byte, 18)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	n := base64.RawURLEncoding.EncodeToString(nonce)
+	return n + "." + shareSignature(n, postKey), nil
+}
+
+func shareSignature(nonce, postKey string) string {
+	mac := hmac.New(sha256.New, GetJWTSecret())
+	mac.Write([]byte("share:" + nonce + ":" + postKey))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validShareToken reports whether token was signed for postKey.
+func validShareToken(token, postKey string) bool {
+	nonce, sig, ok := strings.Cut(token, ".")
+	return ok && nonce != "" && hmac.Equal([]byte(sig), []byte(shareSignature(nonce, postKey)))
+}
+
+// shareLink returns the link named by the request's share token if it belongs
+// to post, or nil. The link may have expired or been revoked.
+func (s *Server) shareLink(c echo.Context, post *types.Post) *types.ShareLink {
+	token := c.QueryParam(shareParam)
+	if token == "" || !validShareToken(token, post.PostKey) {
+		return nil
+	}
+	link, err := s.db.ShareLinkByToken(token)
+	if err != nil {
+		log.Error("Failed to read share link", "post", post.PostKey, "error", err)
+		return nil
+	}
+	if link == nil || link.PostID != post.ID {
+		return nil
+	}
+	return link
+}
+
+// sharedMedia reports whether the request carries a live share link exposing
+// the blob of post. Media requests don't count as uses, only opening the post
+// does, so a limited link still shows every image it allows.
+func (s *Server) sharedMedia(c echo.Context, post *types.Post, blobID uint) bool {
+	link := s.shareLink(c, post)
+	if link == nil || !link.Live(time.Now()) {
+		return false
+	}
+	if len(link.Media) > 0 {
+		info, err := s.db.GetImageBlobInfo(blobID)
+		if err != nil {
+			log.Error("Failed to read shared blob", "post", post.PostKey, "blob", blobID, "error", err)
+			return false
+		}
+		if !link.AllowsMedia(info.Checksum) {
+			return false
+		}
+	}
+	log.Info("Share link media request", "link", link.ID, "post", post.PostKey, "blob", blobID, "ip", c.RealIP())
+	return true
+}
+
+// serveSharedPost answers GET /posts/:id?share=. Each call uses up one of the
+// link's uses and is written to the audit log. The post is returned even if
+// unpublished, with only the media the link exposes.
+func (s *Server) serveSharedPost(c echo.Context, post *types.Post) error {
+	link := s.shareLink(c, post)
+	if link == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+	}
+	ok, err := s.db.UseShareLink(link.ID, time.Now())
+	if err != nil {
+		log.Error("Failed to use share link", "link", link.ID, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to open share link"})
+	}
+	if !ok {
+		return c.JSON(http.StatusGone, map[string]string{"error": "Share link has expired"})
+	}
+
+	s.audit(c, types.AuditShareUse, "post", post.PostKey, nil, shareUse{LinkID: link.ID})
+
+	if len(link.Media) > 0 {
+		images := make([]types.Image, 0, len(post.Images))
+		for _, img := range post.Images {
+			if link.AllowsImage(img) {
+				images = append(images, img)
+			}
+		}
+		post.Images = images
+	}
+	return c.JSON(http.StatusOK, post)
+}
+
+// handleGetShares lists the share links of a post.
+func (s *Server) handleGetShares(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	post, err := s.db.ReadPostByExternalID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+	}
+	links, err := s.db.PostShareLinks(post.ID)
+	if err != nil {
+		log.Error("Failed to list share links", "id", post.PostKey, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list share links"})
+	}
+	host := cmp.Or(s.config.PublicURL, getHost(c))
+	resp := make([]shareResponse, len(links))
+	for n, l := range links {
+		resp[n] = shareResponse{ShareLink: l, URL: shareURL(host, l)}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// handleCreateShare creates a share link for a post.
+func (s *Server) handleCreateShare(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	var req shareRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+	if !req.ExpiresAt.After(time.Now()) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "expiresAt must be in the future"})
+	}
+	if req.MaxUses < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "maxUses must not be negative"})
+	}
+
+	post, err := s.db.ReadPostByExternalID(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Post not found"})
+	}
+	// Indices are resolved to checksums now, so later edits to the post
+	// can't change what the link exposes.
+	var media []string
+	for _, n := range req.Media {
+		if n < 0 || n >= len(post.Images) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("media index %d is out of range", n)})
+		}
+		for _, b := range post.Images[n].Blobs {
+			media = append(media, b.Checksum)
+		}
+	}
+	slices.Sort(media)
+
+	token, err := newShareToken(post.PostKey)
+	if err != nil {
+		log.Error("Failed to generate share token", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create share link"})
+	}
+	link := &types.ShareLink{
+		Token:     token,
+		PostID:    post.ID,
+		PostKey:   post.PostKey,
+		ExpiresAt: req.ExpiresAt.UTC(),
+		MaxUses:   req.MaxUses,
+		Media:     slices.Compact(media),
+		CreatedBy: user.UserID,
+	}
+	if err := s.db.CreateShareLink(link); err != nil {
+		log.Error("Failed to save share link", "id", post.PostKey, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create share link"})
+	}
+	s.audit(c, types.AuditShareCreate, "share", strconv.FormatUint(uint64(link.ID), 10), types.AuditDiff(nil, map[string]any{
+		"post":      post.PostKey,
+		"expiresAt": link.ExpiresAt.Format(time.RFC3339),
+		"maxUses":   link.MaxUses,
+		"media":     link.Media,
+	}), nil)
+	log.Info("Share link created", "link", link.ID, "post", post.PostKey, "by", user.UserID, "expires", link.ExpiresAt)
+	return c.JSON(http.StatusCreated, shareResponse{ShareLink: *link, URL: shareURL(cmp.Or(s.config.PublicURL, getHost(c)), *link)})
+}
+
+// handleRevokeShare revokes a share link.
+func (s *Server) handleRevokeShare(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid share link ID"})
+	}
+	if err := s.db.RevokeShareLink(uint(id), time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Share link not found"})
+		}
+		log.Error("Failed to revoke share link", "link", id, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to revoke share link"})
+	}
+	s.audit(c, types.AuditShareRevoke, "share", c.Param("id"), nil, nil)
+	log.Info("Share link revoked", "link", id, "by", user.UserID)
+	return c.NoContent(http.StatusNoContent)
+}
+
+func shareURL(host string, l types.ShareLink) string {
+	return fmt.Sprintf("%s/post/%s?%s=%s", host, l.PostKey, shareParam, l.Token)
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"drigo/pkg/sqlite"
+	"drigo/pkg/types"
+)
+
+// parseTags turns the "tags" form field into tags to attach; the database
+// resolves them by slug and creates any that don't exist yet.
+func parseTags(names []string) []types.Tag {
+	tags := make([]types.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, types.Tag{Name: name})
+	}
+	return tags
+}
+
+func (s *Server) handleGetTags(c echo.Context) error {
+	tags, err := s.db.ListTags()
+	if err != nil {
+		log.Error("Failed to list tags", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list tags"})
+	}
+	if tags == nil {
+		tags = []types.Tag{}
+	}
+	return c.JSON(http.StatusOK, tags)
+}
+
+type tagRequest struct {
+	Name string `json:"name" form:"name"`
+}
+
+func (s *Server) handleCreateTag(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	var req tagRequest
+	if err := c.Bind(&req); err != nil || sqlite.TagSlug(req.Name) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid tag name"})
+	}
+
+	tag, err := s.db.CreateTag(req.Name)
+	if err != nil {
+		return tagErrorResponse(c, "Failed to create tag", err)
+	}
+	s.audit(c, types.AuditTagCreate, "tag", strconv.FormatUint(uint64(tag.ID), 10), types.AuditDiff(nil, map[string]any{"name": tag.Name}), nil)
+	return c.JSON(http.StatusCreated, tag)
+}
+
+func (s *Server) handleRenameTag(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid tag id"})
+	}
+	var req tagRequest
+	if err := c.Bind(&req); err != nil || sqlite.TagSlug(req.Name) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid tag name"})
+	}
+
+	oldName := s.tagName(uint(id))
+	tag, err := s.db.RenameTag(uint(id), req.Name)
+	if err != nil {
+		return tagErrorResponse(c, "Failed to rename tag", err)
+	}
+	s.getPostCache.Reset()
+	s.audit(c, types.AuditTagUpdate, "tag", c.Param("id"), types.AuditDiff(map[string]any{"name": oldName}, map[string]any{"name": tag.Name}), nil)
+	return c.JSON(http.StatusOK, tag)
+}
+
+func (s *Server) handleDeleteTag(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid tag id"})
+	}
+	oldName := s.tagName(uint(id))
+	if err := s.db.DeleteTag(uint(id)); err != nil {
+		return tagErrorResponse(c, "Failed to delete tag", err)
+	}
+	s.getPostCache.Reset()
+	s.audit(c, types.AuditTagDelete, "tag", c.Param("id"), types.AuditDiff(map[string]any{"name": oldName}, nil), nil)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// tagName returns the name of a tag for the audit log, or "" if it can't be
+// found.
+func (s *Server) tagName(id uint) string {
+	tags, err := s.db.ListTags()
+	if err != nil {
+		return ""
+	}
+	for _, t := range tags {
+		if t.ID == id {
+			return t.Name
+		}
+	}
+	return ""
+}
+
+// tagErrorResponse maps tag store errors to a JSON response.
+func tagErrorResponse(c echo.Context, msg string, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Tag not found"})
+	case errors.Is(err, sqlite.ErrTagExists):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Tag already exists"})
+	}
+	log.Error(msg, "error", err)
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": msg})
+}
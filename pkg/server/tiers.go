@@ -0,0 +1,119 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+
+	"drigo/pkg/access"
+	"drigo/pkg/sqlite"
+	"drigo/pkg/types"
+)
+
+// tiers returns the cached tier list used by the access checks.
+func (s *Server) tiers() access.Tiers {
+	tiers, err := s.tierCache.Get(struct{}{})
+	if err != nil {
+		log.Error("Failed to load tiers", "error", err)
+	}
+	return tiers
+}
+
+// parseMinTier reads the minTier form field: the ID of an existing tier, or
+// empty for none. ok is false if the value names no tier.
+func (s *Server) parseMinTier(value string) (id *uint, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, false
+	}
+	tiers, err := s.db.ListTiers()
+	if err != nil {
+		log.Error("Failed to list tiers", "error", err)
+		return nil, false
+	}
+	for _, t := range tiers {
+		if t.ID == uint(parsed) {
+			return &t.ID, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Server) handleGetTiers(c echo.Context) error {
+	tiers, err := s.db.ListTiers()
+	if err != nil {
+		log.Error("Failed to list tiers", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list tiers"})
+	}
+	if tiers == nil {
+		tiers = []types.Tier{}
+	}
+	return c.JSON(http.StatusOK, tiers)
+}
+
+// tierRequest is one entry of the PUT /tiers body, which lists tiers from
+// lowest to highest.
+type tierRequest struct {
+	RoleID string `json:"roleId"`
+	Name   string `json:"name"`
+}
+
+// handleSetTiers replaces the ordered tier list.
+func (s *Server) handleSetTiers(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	var req []tierRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid tiers"})
+	}
+	seen := make(map[string]bool, len(req))
+	tiers := make([]types.Tier, 0, len(req))
+	for _, t := range req {
+		roleID := strings.TrimSpace(t.RoleID)
+		if roleID == "" || seen[roleID] {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Each tier needs a distinct roleId"})
+		}
+		seen[roleID] = true
+		tiers = append(tiers, types.Tier{RoleID: roleID, Name: t.Name})
+	}
+
+	before, err := s.db.ListTiers()
+	if err != nil {
+		log.Error("Failed to list tiers", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save tiers"})
+	}
+	saved, err := s.db.SetTiers(tiers)
+	if err != nil {
+		if errors.Is(err, sqlite.ErrTierInUse) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "A removed tier is still required by posts"})
+		}
+		log.Error("Failed to save tiers", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save tiers"})
+	}
+	s.tierCache.Reset()
+	s.audit(c, types.AuditTiers, "tiers", "", types.AuditDiff(
+		map[string]any{"tiers": tierNames(before)},
+		map[string]any{"tiers": tierNames(saved)},
+	), nil)
+	return c.JSON(http.StatusOK, saved)
+}
+
+// tierNames lists tiers as "roleId:name", lowest first, for the audit log.
+func tierNames(tiers []types.Tier) []string {
+	names := make([]string, len(tiers))
+	for n, t := range tiers {
+		names[n] = t.RoleID + ":" + t.Name
+	}
+	return names
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"drigo/pkg/sqlite"
+	"drigo/pkg/types"
+)
+
+// trashPurgeInterval is how often trash past its retention is purged.
+const trashPurgeInterval = time.Hour
+
+// runTrashPurge periodically purges posts and media that have been in the
+// trash longer than the configured retention. A zero retention keeps the
+// trash until it is purged by hand.
+func (s *Server) runTrashPurge(ctx context.Context) {
+	if s.config.TrashRetention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		s.purgeTrash()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) purgeTrash() {
+	n, err := s.db.PurgeTrash(time.Now().Add(-s.config.TrashRetention))
+	if err != nil {
+		log.Error("Failed to purge trash", "error", err)
+		return
+	}
+	if n > 0 {
+		log.Info("Purged trash", "items", n)
+	}
+}
+
+// trashResponse is the JSON body of GET /trash.
+type trashResponse struct {
+	Posts []*types.Post `json:"posts"`
+	Media []types.Image `json:"media"`
+}
+
+// handleGetTrash lists deleted posts and media replaced on live posts, most
+// recently trashed first. Both lists are paged together.
+func (s *Server) handleGetTrash(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	posts, err := s.db.TrashedPosts(limit, (page-1)*limit)
+	if err != nil {
+		log.Error("Failed to list trashed posts", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list trash"})
+	}
+	media, err := s.db.TrashedMedia(limit, (page-1)*limit)
+	if err != nil {
+		log.Error("Failed to list trashed media", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list trash"})
+	}
+	res := trashResponse{Posts: posts, Media: media}
+	if res.Posts == nil {
+		res.Posts = []*types.Post{}
+	}
+	if res.Media == nil {
+		res.Media = []types.Image{}
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// handleRestoreTrashedPost brings a deleted post back with its media.
+// Deleted announcements are not sent again.
+func (s *Server) handleRestoreTrashedPost(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid id"})
+	}
+
+	post, err := s.db.RestoreTrashedPost(uint(id))
+	if err != nil {
+		if status, msg := trashError(err, "Post"); status != 0 {
+			return c.JSON(status, map[string]string{"error": msg})
+		}
+		log.Error("Failed to restore trashed post", "id", id, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to restore post"})
+	}
+	s.getPostCache.Reset()
+	s.wakeScheduler()
+	s.audit(c, types.AuditTrashRestore, "post", post.PostKey, nil, nil)
+
+	log.Info("Post restored from trash", "id", post.PostKey, "by", user.Username)
+	return c.JSON(http.StatusOK, post)
+}
+
+// handleRestoreTrashedMedia puts replaced media back on its post.
+func (s *Server) handleRestoreTrashedMedia(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid id"})
+	}
+
+	post, err := s.db.RestoreTrashedMedia(uint(id))
+	if err != nil {
+		if status, msg := trashError(err, "Media"); status != 0 {
+			return c.JSON(status, map[string]string{"error": msg})
+		}
+		log.Error("Failed to restore trashed media", "id", id, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to restore media"})
+	}
+	s.getPostCache.Reset()
+	s.refreshAnnouncements(post, getHost(c))
+	s.audit(c, types.AuditTrashRestore, "media", c.Param("id"), nil, map[string]string{"post": post.PostKey})
+
+	log.Info("Media restored from trash", "id", id, "post", post.PostKey, "by", user.Username)
+	return c.JSON(http.StatusOK, post)
+}
+
+// handlePurgeTrashedPost permanently deletes a post in the trash.
+func (s *Server) handlePurgeTrashedPost(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid id"})
+	}
+
+	if err := s.db.PurgePost(uint(id)); err != nil {
+		if status, msg := trashError(err, "Post"); status != 0 {
+			return c.JSON(status, map[string]string{"error": msg})
+		}
+		log.Error("Failed to purge post", "id", id, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to purge post"})
+	}
+
+	s.audit(c, types.AuditTrashPurge, "post", c.Param("id"), nil, nil)
+	log.Info("Post purged", "id", id, "by", user.Username)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// handlePurgeTrashedMedia permanently deletes replaced media in the trash.
+func (s *Server) handlePurgeTrashedMedia(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid id"})
+	}
+
+	if err := s.db.PurgeMedia(uint(id)); err != nil {
+		if status, msg := trashError(err, "Media"); status != 0 {
+			return c.JSON(status, map[string]string{"error": msg})
+		}
+		log.Error("Failed to purge media", "id", id, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to purge media"})
+	}
+
+	s.audit(c, types.AuditTrashPurge, "media", c.Param("id"), nil, nil)
+	log.Info("Media purged", "id", id, "by", user.Username)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// trashError maps the expected errors of trash operations on what to a
+// response, or returns a zero status for anything else.
+func trashError(err error, what string) (int, string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound, what + " not found"
+	case errors.Is(err, sqlite.ErrNotTrashed):
+		return http.StatusConflict, what + " is not in the trash"
+	case errors.Is(err, sqlite.ErrPostTrashed):
+		return http.StatusConflict, "Post is in the trash; restore the post instead"
+	}
+	return 0, ""
+}
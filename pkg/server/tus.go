@@ -0,0 +1,381 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+	"github.com/segmentio/ksuid"
+
+	"drigo/pkg/types"
+)
+
+// Resumable uploads follow the tus 1.0.0 protocol (https://tus.io/protocols/resumable-upload)
+// with the creation, termination and expiration extensions. Finished uploads
+// are attached to posts through the "uploads" form field or "u:<id>" tokens in
+// mediaOrder.
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,expiration"
+	// uploadTTL is how long an upload may sit idle before the janitor removes it.
+	uploadTTL = 24 * time.Hour
+	// uploadJanitorInterval is how often expired uploads are swept.
+	uploadJanitorInterval = 15 * time.Minute
+)
+
+// tusHeaders are the request and response headers browsers need CORS access to.
+var tusHeaders = []string{"Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Length", "Upload-Offset", "Upload-Metadata", "Upload-Expires"}
+
+// tusMiddleware stamps Tus-Resumable on every response and rejects requests
+// speaking another protocol version.
+func tusMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set("Tus-Resumable", tusVersion)
+		if c.Request().Method != http.MethodOptions && c.Request().Header.Get("Tus-Resumable") != tusVersion {
+			c.Response().Header().Set("Tus-Version", tusVersion)
+			return c.JSON(http.StatusPreconditionFailed, map[string]string{"error": "Unsupported tus version"})
+		}
+		return next(c)
+	}
+}
+
+func (s *Server) uploadPath(id string) string {
+	return filepath.Join(s.config.UploadDir, id)
+}
+
+// uploadOwner returns the upload identified by the :id param if it belongs to
+// the requesting admin. It writes the error response itself and returns nil
+// otherwise.
+func (s *Server) uploadOwner(c echo.Context) (*types.Upload, error) {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return nil, c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+	upload, err := s.db.GetUpload(c.Param("id"))
+	if err != nil {
+		log.Error("Failed to read upload", "id", c.Param("id"), "error", err)
+		return nil, c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read upload"})
+	}
+	if upload == nil || upload.UserID != user.UserID {
+		return nil, c.JSON(http.StatusNotFound, map[string]string{"error": "Upload not found"})
+	}
+	return upload, nil
+}
+
+func setUploadHeaders(c echo.Context, upload *types.Upload) {
+	h := c.Response().Header()
+	h.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	h.Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	h.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	h.Set(echo.HeaderCacheControl, "no-store")
+}
+
+func (s *Server) handleUploadOptions(c echo.Context) error {
+	h := c.Response().Header()
+	h.Set("Tus-Version", tusVersion)
+	h.Set("Tus-Extension", tusExtensions)
+	if s.config.MaxFileSize > 0 {
+		h.Set("Tus-Max-Size", strconv.FormatInt(s.config.MaxFileSize, 10))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (s *Server) handleCreateUpload(c echo.Context) error {
+	user := s.getEffectiveUser(c)
+	if user == nil || !user.IsAdmin {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	length, err := strconv.ParseInt(c.Request().Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Missing or invalid Upload-Length"})
+	}
+	if limit := s.config.MaxFileSize; limit > 0 && length > limit {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("Upload exceeds the %s file size limit", formatSize(limit))})
+	}
+
+	meta := parseUploadMetadata(c.Request().Header.Get("Upload-Metadata"))
+	upload := &types.Upload{
+		ID:          ksuid.New().String(),
+		UserID:      user.UserID,
+		Filename:    filepath.Base(cmp.Or(meta["filename"], meta["name"], "upload")),
+		ContentType: cmp.Or(meta["filetype"], meta["type"]),
+		Length:      length,
+		ExpiresAt:   time.Now().Add(uploadTTL),
+	}
+	if upload.HashState, err = sha256.New().(encoding.BinaryMarshaler).MarshalBinary(); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create upload"})
+	}
+
+	f, err := os.OpenFile(s.uploadPath(upload.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Error("Failed to create upload file", "id", upload.ID, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create upload"})
+	}
+	f.Close()
+
+	if length == 0 {
+		upload.Checksum = hex.EncodeToString(sha256.New().Sum(nil))
+		upload.HashState = nil
+	}
+	if err := s.db.CreateUpload(upload); err != nil {
+		os.Remove(s.uploadPath(upload.ID))
+		log.Error("Failed to save upload", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create upload"})
+	}
+
+	log.Info("Upload created", "id", upload.ID, "filename", upload.Filename, "length", length, "by", user.Username)
+	c.Response().Header().Set(echo.HeaderLocation, "/uploads/"+upload.ID)
+	setUploadHeaders(c, upload)
+	return c.NoContent(http.StatusCreated)
+}
+
+func (s *Server) handleHeadUpload(c echo.Context) error {
+	upload, err := s.uploadOwner(c)
+	if upload == nil {
+		return err
+	}
+	setUploadHeaders(c, upload)
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *Server) handlePatchUpload(c echo.Context) error {
+	upload, err := s.uploadOwner(c)
+	if upload == nil {
+		return err
+	}
+
+	req := c.Request()
+	if req.Header.Get(echo.HeaderContentType) != "application/offset+octet-stream" {
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]string{"error": "Content-Type must be application/offset+octet-stream"})
+	}
+	offset, err := strconv.ParseInt(req.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Missing or invalid Upload-Offset"})
+	}
+
+	// Only one PATCH may append to an upload at a time.
+	if _, busy := s.uploadLocks.LoadOrStore(upload.ID, struct{}{}); busy {
+		return c.JSON(http.StatusLocked, map[string]string{"error": "Upload is in use"})
+	}
+	defer s.uploadLocks.Delete(upload.ID)
+
+	// Re-read now that we hold the lock so the offset can't be stale.
+	if upload, err = s.db.GetUpload(upload.ID); err != nil || upload == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Upload not found"})
+	}
+	if offset != upload.Offset {
+		setUploadHeaders(c, upload)
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Upload-Offset does not match"})
+	}
+	if upload.Complete() {
+		setUploadHeaders(c, upload)
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	n, writeErr := s.appendUpload(upload, req.Body)
+	if n > 0 || writeErr == nil {
+		upload.ExpiresAt = time.Now().Add(uploadTTL)
+		if err := s.db.SaveUploadProgress(upload); err != nil {
+			log.Error("Failed to save upload progress", "id", upload.ID, "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save upload"})
+		}
+	}
+	if writeErr != nil {
+		// Bytes received before the failure are kept; the client resumes from
+		// the offset reported by HEAD.
+		log.Warn("Upload chunk interrupted", "id", upload.ID, "received", n, "error", writeErr)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to write upload"})
+	}
+
+	setUploadHeaders(c, upload)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// appendUpload writes r to the end of the upload's file, hashing as it goes,
+// and advances Offset by the bytes written. When the upload completes its
+// checksum and content type are filled in.
+func (s *Server) appendUpload(upload *types.Upload, r io.Reader) (int64, error) {
+	hash := sha256.New()
+	if err := hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(upload.HashState); err != nil {
+		return 0, fmt.Errorf("restore hash state: %w", err)
+	}
+
+	f, err := os.OpenFile(s.uploadPath(upload.ID), os.O_WRONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	// Drop anything written past the recorded offset by an earlier crash.
+	if err := f.Truncate(upload.Offset); err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(upload.Offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, copyErr := io.Copy(io.MultiWriter(f, hash), io.LimitReader(r, upload.Length-upload.Offset))
+	if n == 0 && copyErr != nil {
+		return 0, copyErr
+	}
+	if err := f.Sync(); err != nil {
+		return 0, errors.Join(copyErr, err)
+	}
+
+	upload.Offset += n
+	if upload.HashState, err = hash.(encoding.BinaryMarshaler).MarshalBinary(); err != nil {
+		return n, errors.Join(copyErr, err)
+	}
+	if upload.Complete() {
+		upload.Checksum = hex.EncodeToString(hash.Sum(nil))
+		upload.HashState = nil
+		if upload.ContentType == "" || upload.ContentType == echo.MIMEOctetStream {
+			upload.ContentType = sniffFile(s.uploadPath(upload.ID))
+		}
+	}
+	return n, copyErr
+}
+
+func (s *Server) handleDeleteUpload(c echo.Context) error {
+	upload, err := s.uploadOwner(c)
+	if upload == nil {
+		return err
+	}
+	if _, busy := s.uploadLocks.LoadOrStore(upload.ID, struct{}{}); busy {
+		return c.JSON(http.StatusLocked, map[string]string{"error": "Upload is in use"})
+	}
+	defer s.uploadLocks.Delete(upload.ID)
+
+	if err := s.removeUpload(upload.ID); err != nil {
+		log.Error("Failed to delete upload", "id", upload.ID, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete upload"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// removeUpload deletes an upload's record and its file.
+func (s *Server) removeUpload(id string) error {
+	if err := s.db.DeleteUpload(id); err != nil {
+		return err
+	}
+	if err := os.Remove(s.uploadPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// resolveUploads looks up the completed uploads named in the "uploads" form
+// field (repeated or comma separated) and returns them as spooled files in the
+// same order. Uploads must belong to userID.
+func (s *Server) resolveUploads(form *multipartUpload, userID string) ([]*uploadedFile, []string, error) {
+	var files []*uploadedFile
+	var ids []string
+	for _, id := range form.List("uploads") {
+		upload, err := s.db.GetUpload(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if upload == nil || upload.UserID != userID {
+			return nil, nil, fmt.Errorf("upload %s not found", id)
+		}
+		if !upload.Complete() {
+			return nil, nil, fmt.Errorf("upload %s is not complete", id)
+		}
+		files = append(files, &uploadedFile{
+			Filename:    upload.Filename,
+			ContentType: upload.ContentType,
+			Size:        upload.Length,
+			Checksum:    upload.Checksum,
+			path:        s.uploadPath(upload.ID),
+		})
+		ids = append(ids, id)
+	}
+	return files, ids, nil
+}
+
+// finishUploads removes uploads once their content has been stored with a post.
+func (s *Server) finishUploads(ids []string) {
+	for _, id := range ids {
+		if err := s.removeUpload(id); err != nil {
+			log.Warn("Failed to remove attached upload", "id", id, "error", err)
+		}
+	}
+}
+
+// runUploadJanitor periodically removes uploads that were abandoned.
+func (s *Server) runUploadJanitor(ctx context.Context) {
+	ticker := time.NewTicker(uploadJanitorInterval)
+	defer ticker.Stop()
+	for {
+		s.sweepUploads()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) sweepUploads() {
+	expired, err := s.db.ExpiredUploads(time.Now())
+	if err != nil {
+		log.Error("Failed to list expired uploads", "error", err)
+		return
+	}
+	for _, upload := range expired {
+		if _, busy := s.uploadLocks.LoadOrStore(upload.ID, struct{}{}); busy {
+			continue
+		}
+		if err := s.removeUpload(upload.ID); err != nil {
+			log.Warn("Failed to remove expired upload", "id", upload.ID, "error", err)
+		} else {
+			log.Info("Removed expired upload", "id", upload.ID, "filename", upload.Filename)
+		}
+		s.uploadLocks.Delete(upload.ID)
+	}
+}
+
+// parseUploadMetadata decodes an Upload-Metadata header: comma separated
+// "key base64value" pairs, where the value may be omitted.
+func parseUploadMetadata(header string) map[string]string {
+	meta := make(map[string]string)
+	for pair := range strings.SplitSeq(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		meta[key] = string(decoded)
+	}
+	return meta
+}
+
+// sniffFile detects the content type of a file from its first 512 bytes.
+func sniffFile(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return echo.MIMEOctetStream
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, _ := io.ReadFull(f, buf)
+	return http.DetectContentType(buf[:n])
+}
@@ -0,0 +1,272 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+
+	"drigo/pkg/types"
+	"drigo/pkg/units"
+)
+
+// maxFormValueSize bounds non-file multipart fields (titles, role lists...).
+const maxFormValueSize = 1 * units.Mebibyte
+
+// errUploadTooLarge is returned when a request or file exceeds its size limit.
+type errUploadTooLarge struct {
+	msg string
+}
+
+func (e *errUploadTooLarge) Error() string { return e.msg }
+
+// uploadedFile is a multipart file part spooled to a temp file. Its size,
+// SHA-256 and sniffed content type are computed while it streams in.
+type uploadedFile struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	Checksum    string
+	path        string
+}
+
+// Open opens the spooled file for reading.
+func (f *uploadedFile) Open() (*os.File, error) {
+	return os.Open(f.path)
+}
+
+// ReadAll loads the spooled file into memory. Only use it for payloads that
+// must be decoded in full, such as thumbnails.
+func (f *uploadedFile) ReadAll() ([]byte, error) {
+	return os.ReadFile(f.path)
+}
+
+// IsImage reports whether the sniffed content type is a still or animated image.
+func (f *uploadedFile) IsImage() bool {
+	return strings.HasPrefix(f.ContentType, "image/")
+}
+
+// multipartUpload holds the fields and spooled files of a streamed multipart
+// request. Call Cleanup once the files are no longer needed.
+type multipartUpload struct {
+	values url.Values
+	files  map[string][]*uploadedFile
+	opened []*os.File
+}
+
+// Value returns the first value of a form field, like echo.Context.FormValue.
+func (u *multipartUpload) Value(name string) string {
+	return u.values.Get(name)
+}
+
+// Has reports whether the form included the field at all, even empty.
+func (u *multipartUpload) Has(name string) bool {
+	_, ok := u.values[name]
+	return ok
+}
+
+// List returns the non-empty values of a field that may be repeated or
+// comma separated.
+func (u *multipartUpload) List(name string) []string {
+	var list []string
+	for _, value := range u.values[name] {
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// Files returns the files uploaded under the first non-empty field in names.
+func (u *multipartUpload) Files(names ...string) []*uploadedFile {
+	for _, name := range names {
+		if files := u.files[name]; len(files) > 0 {
+			return files
+		}
+	}
+	return nil
+}
+
+// Blob opens f and returns an ImageBlob that streams it into the blob store.
+// The file is closed by Cleanup.
+func (u *multipartUpload) Blob(f *uploadedFile) (types.ImageBlob, error) {
+	file, err := f.Open()
+	if err != nil {
+		return types.ImageBlob{}, err
+	}
+	u.opened = append(u.opened, file)
+	return types.ImageBlob{
+		Source:      file,
+		Checksum:    f.Checksum,
+		Size:        f.Size,
+		ContentType: f.ContentType,
+		Filename:    f.Filename,
+	}, nil
+}
+
+// Cleanup closes opened readers and removes every spooled file. It is safe to
+// call more than once.
+func (u *multipartUpload) Cleanup() {
+	for _, f := range u.opened {
+		f.Close()
+	}
+	u.opened = nil
+	for _, files := range u.files {
+		for _, f := range files {
+			if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Warn("Failed to remove spooled upload", "path", f.path, "error", err)
+			}
+		}
+	}
+	clear(u.files)
+}
+
+// readUpload streams a multipart request part by part, spooling files to disk
+// instead of buffering them in memory. It enforces Config.MaxUploadSize for
+// the whole request and Config.MaxFileSize for each file.
+func (s *Server) readUpload(c echo.Context) (*multipartUpload, error) {
+	req := c.Request()
+	if s.config.MaxUploadSize > 0 {
+		if req.ContentLength > s.config.MaxUploadSize {
+			return nil, &errUploadTooLarge{msg: fmt.Sprintf("Request exceeds the %s upload limit", formatSize(s.config.MaxUploadSize))}
+		}
+		req.Body = http.MaxBytesReader(c.Response(), req.Body, s.config.MaxUploadSize)
+	}
+
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &multipartUpload{values: url.Values{}, files: map[string][]*uploadedFile{}}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return upload, nil
+		}
+		if err != nil {
+			upload.Cleanup()
+			return nil, s.uploadError(err)
+		}
+
+		name := part.FormName()
+		if name == "" {
+			part.Close()
+			continue
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormValueSize+1))
+			part.Close()
+			if err != nil {
+				upload.Cleanup()
+				return nil, s.uploadError(err)
+			}
+			if len(value) > maxFormValueSize {
+				upload.Cleanup()
+				return nil, &errUploadTooLarge{msg: fmt.Sprintf("Field %q is too large", name)}
+			}
+			upload.values.Add(name, string(value))
+			continue
+		}
+
+		file, err := s.spoolPart(part)
+		part.Close()
+		if file != nil {
+			upload.files[name] = append(upload.files[name], file)
+		}
+		if err != nil {
+			upload.Cleanup()
+			return nil, s.uploadError(err)
+		}
+	}
+}
+
+// spoolPart copies a file part to a temp file, hashing it and sniffing its
+// content type on the way. The returned file is non-nil whenever a temp file
+// was created so the caller can clean it up on error.
+func (s *Server) spoolPart(part *multipart.Part) (*uploadedFile, error) {
+	tmp, err := os.CreateTemp("", "aegis-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer tmp.Close()
+
+	file := &uploadedFile{Filename: filepath.Base(part.FileName()), path: tmp.Name()}
+
+	var src io.Reader = part
+	if limit := s.config.MaxFileSize; limit > 0 {
+		src = io.LimitReader(part, limit+1)
+	}
+
+	hash := sha256.New()
+	sniff := &sniffBuffer{}
+	n, err := io.Copy(io.MultiWriter(tmp, hash, sniff), src)
+	if err != nil {
+		return file, err
+	}
+	if limit := s.config.MaxFileSize; limit > 0 && n > limit {
+		return file, &errUploadTooLarge{msg: fmt.Sprintf("File %q exceeds the %s file size limit", file.Filename, formatSize(limit))}
+	}
+
+	file.Size = n
+	file.Checksum = hex.EncodeToString(hash.Sum(nil))
+	file.ContentType = part.Header.Get(echo.HeaderContentType)
+	if file.ContentType == "" || file.ContentType == echo.MIMEOctetStream {
+		file.ContentType = http.DetectContentType(sniff.buf)
+	}
+	return file, nil
+}
+
+// sniffBuffer keeps the first 512 bytes written to it for content sniffing.
+type sniffBuffer struct {
+	buf []byte
+}
+
+func (b *sniffBuffer) Write(p []byte) (int, error) {
+	if room := 512 - len(b.buf); room > 0 {
+		b.buf = append(b.buf, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// uploadError maps body read failures to a 413 when a limit was hit.
+func (s *Server) uploadError(err error) error {
+	if _, ok := errors.AsType[*http.MaxBytesError](err); ok {
+		return &errUploadTooLarge{msg: fmt.Sprintf("Request exceeds the %s upload limit", formatSize(s.config.MaxUploadSize))}
+	}
+	return err
+}
+
+// uploadErrorResponse writes the JSON error for a failed readUpload.
+func uploadErrorResponse(c echo.Context, err error) error {
+	if tooLarge, ok := errors.AsType[*errUploadTooLarge](err); ok {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": tooLarge.msg})
+	}
+	log.Error("Failed to read multipart upload", "error", err)
+	return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to parse multipart form"})
+}
+
+// formatSize renders a byte count using binary units for error messages.
+func formatSize(n int64) string {
+	switch {
+	case n >= units.Gibibyte && n%units.Gibibyte == 0:
+		return fmt.Sprintf("%dGiB", n/units.Gibibyte)
+	case n >= units.Mebibyte:
+		return fmt.Sprintf("%dMiB", n/units.Mebibyte)
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
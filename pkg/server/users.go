@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+
+	"drigo/pkg/types"
 )
 
 func (s *Server) handleGetUsers(c echo.Context) error {
@@ -37,9 +39,14 @@ func (s *Server) handleToggleAdmin(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 	}
 
+	var wasAdmin bool
+	if target, err := s.db.UserByID(id); err == nil && target != nil {
+		wasAdmin = target.IsAdmin
+	}
 	if err := s.db.SetAdmin(id, req.IsAdmin); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update admin status"})
 	}
+	s.audit(c, types.AuditUserAdmin, "user", id, types.AuditDiff(map[string]any{"isAdmin": wasAdmin}, map[string]any{"isAdmin": req.IsAdmin}), nil)
 
 	return c.JSON(http.StatusOK, map[string]any{"success": true, "isAdmin": req.IsAdmin})
 }
@@ -0,0 +1,114 @@
+package sqlite
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"drigo/pkg/types"
+)
+
+// ErrNotArchived is returned when restoring a post that isn't archived.
+var ErrNotArchived = errors.New("post is not archived")
+
+// NextExpiresAt returns the earliest expiry of a published post not yet
+// archived, or nil if there is none.
+func (s *sqliteDB) NextExpiresAt() (*time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var posts []types.Post
+	err := s.db.Select("expires_at").
+		Where("expires_at IS NOT NULL AND archived_at IS NULL AND published_at IS NOT NULL").
+		Order("expires_at").
+		Limit(1).
+		Find(&posts).Error
+	if err != nil || len(posts) == 0 {
+		return nil, err
+	}
+	return posts[0].ExpiresAt, nil
+}
+
+// ArchiveDuePosts archives every published post whose ExpiresAt has passed at
+// now and returns them. Drafts and scheduled posts wait until they are
+// published. Each post is returned by exactly one call.
+func (s *sqliteDB) ArchiveDuePosts(now time.Time) ([]*types.Post, error) {
+	now = now.UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&types.Post{}).
+			Where("expires_at <= ? AND archived_at IS NULL AND published_at IS NOT NULL", now).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Model(&types.Post{}).Where("id IN ?", ids).Update("archived_at", now).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := reindexPost(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var posts []*types.Post
+	if err := listPreloads(s.db).Where("id IN ?", ids).Order("expires_at, id").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// ArchivedPosts returns archived posts, most recently archived first.
+func (s *sqliteDB) ArchivedPosts(limit, offset int) ([]*types.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var posts []*types.Post
+	err := listPreloads(s.db).
+		Where("archived_at IS NOT NULL").
+		Order("archived_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&posts).Error
+	return posts, err
+}
+
+// RestorePost brings an archived post back with a new expiry, or none if
+// expiresAt is nil.
+func (s *sqliteDB) RestorePost(id uint, expiresAt *time.Time) (*types.Post, error) {
+	if expiresAt != nil {
+		expiresAt = new(expiresAt.UTC())
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var p types.Post
+		if err := tx.Select("id", "archived_at").First(&p, id).Error; err != nil {
+			return err
+		}
+		if p.ArchivedAt == nil {
+			return ErrNotArchived
+		}
+		if err := tx.Model(&p).Updates(map[string]any{"archived_at": nil, "expires_at": expiresAt}).Error; err != nil {
+			return err
+		}
+		return reindexPost(tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var p types.Post
+	if err := listPreloads(s.db).First(&p, id).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"strings"
+	"time"
+
+	"drigo/pkg/types"
+)
+
+// AuditQuery filters the audit log. Zero fields match everything.
+type AuditQuery struct {
+	ActorID string
+	// Action matches exactly, or as a prefix when it ends in ".".
+	Action string
+	// Since and Until bound the entry time: Since inclusive, Until exclusive.
+	Since time.Time
+	Until time.Time
+
+	Limit  int
+	Offset int
+}
+
+// AuditEntries returns the audit entries matching q, newest first.
+func (s *sqliteDB) AuditEntries(q AuditQuery) ([]types.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	db := s.db.Model(&types.AuditEntry{})
+	if q.ActorID != "" {
+		db = db.Where("actor_id = ?", q.ActorID)
+	}
+	if prefix, ok := strings.CutSuffix(q.Action, "."); ok {
+		db = db.Where(`action LIKE ? ESCAPE '\'`, strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)+".%")
+	} else if q.Action != "" {
+		db = db.Where("action = ?", q.Action)
+	}
+	if !q.Since.IsZero() {
+		db = db.Where("created_at >= ?", q.Since.UTC())
+	}
+	if !q.Until.IsZero() {
+		db = db.Where("created_at < ?", q.Until.UTC())
+	}
+
+	var entries []types.AuditEntry
+	err := db.Order("created_at DESC, id DESC").Limit(q.Limit).Offset(q.Offset).Find(&entries).Error
+	return entries, err
+}
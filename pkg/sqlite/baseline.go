@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// baselineSchema creates the tables of the last release before versioned
+// migrations, as they stood then. Media payload columns are left out: new
+// databases never get them, and blob_store moves them out of older ones.
+func baselineSchema(db *gorm.DB) error {
+	type Allowed struct {
+		gorm.Model
+		RoleID       string `gorm:"uniqueIndex;size:32"`
+		Name         string
+		Managed      bool
+		Mentionable  bool
+		Hoist        bool
+		Color        int
+		Position     int
+		Permissions  int64
+		Icon         string
+		UnicodeEmoji string
+		Flags        int
+	}
+	type User struct {
+		gorm.Model
+		UserID        string `gorm:"uniqueIndex"`
+		Username      string
+		GlobalName    string
+		Discriminator string
+		Avatar        string
+		Banner        string
+		AccentColor   int
+		Bot           bool
+		System        bool
+		PublicFlags   int
+		IsAdmin       bool
+	}
+	type ImageBlob struct {
+		gorm.Model
+		ImageID     uint `gorm:"index;uniqueIndex:idx_image_blob_order"`
+		Index       int  `gorm:"index;uniqueIndex:idx_image_blob_order"`
+		ContentType string
+		Size        int64
+		Filename    string
+	}
+	type Image struct {
+		gorm.Model
+		PostID       uint        `gorm:"index"`
+		HasThumbnail *bool       `gorm:"->;type:boolean"`
+		Blobs        []ImageBlob `gorm:"constraint:OnDelete:CASCADE"`
+	}
+	type Post struct {
+		gorm.Model
+		PostKey      string    `gorm:"uniqueIndex;size:32"`
+		ChannelID    string    `gorm:"index;size:32"`
+		GuildID      string    `gorm:"index;size:32"`
+		Title        string    `gorm:"type:text"`
+		Description  string    `gorm:"type:text"`
+		Timestamp    time.Time `gorm:"index"`
+		IsPremium    bool
+		FocusX       *float64  `gorm:"default:50"`
+		FocusY       *float64  `gorm:"default:50"`
+		AuthorID     uint      `gorm:"index;default:null"`
+		Author       *User     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+		AllowedRoles []Allowed `gorm:"many2many:post_allowed_roles"`
+		Images       []Image   `gorm:"constraint:OnDelete:CASCADE"`
+	}
+	type Theme struct {
+		BorderRadius       string
+		BorderSize         string
+		GlobalTransparency float64
+
+		PrimaryColorLight    string
+		SecondaryColorLight  string
+		PageBgLight          string
+		PageBgTransLight     float64
+		PageBgImageLight     []byte `gorm:"type:blob"`
+		PageBgImageLightHash string
+		CardBgLight          string
+		CardBgTransLight     float64
+		BorderColorLight     string
+
+		PrimaryColorDark    string
+		SecondaryColorDark  string
+		PageBgDark          string
+		PageBgTransDark     float64
+		PageBgImageDark     []byte `gorm:"type:blob"`
+		PageBgImageDarkHash string
+		CardBgDark          string
+		CardBgTransDark     float64
+		BorderColorDark     string
+	}
+	type Settings struct {
+		gorm.Model
+		HeroTitle       *string
+		HeroSubtitle    *string
+		HeroDescription *string
+		PublicAccess    bool
+		Theme           Theme `gorm:"embedded;embeddedPrefix:theme_"`
+	}
+	type CachedRole struct {
+		ID      string `gorm:"primaryKey"`
+		Name    string
+		Color   int
+		Managed bool
+	}
+	type CachedChannel struct {
+		ID   string `gorm:"primaryKey"`
+		Name string
+		Type int
+	}
+
+	// Early versions put a unique index on images.post_id; posts can have
+	// many images, so drop it before AutoMigrate recreates it as non-unique.
+	if err := db.Exec("DROP INDEX IF EXISTS idx_images_post_id").Error; err != nil {
+		return err
+	}
+	// Order matters for constraints/indexes and FKs;
+	// migrate base tables first, then Post, then child tables.
+	return db.AutoMigrate(
+		&User{},
+		&Allowed{},
+		&Post{},
+		&Image{},
+		&ImageBlob{},
+		&Settings{},
+		&CachedRole{},
+		&CachedChannel{},
+	)
+}
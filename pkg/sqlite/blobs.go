@@ -0,0 +1,461 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/charmbracelet/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"drigo/pkg/blob"
+	"drigo/pkg/types"
+	"drigo/pkg/utils"
+)
+
+// contentPrefix is the blob store prefix for content-addressed payloads.
+const contentPrefix = "sha256"
+
+// contentKey returns the blob store key for a payload with the given SHA-256.
+// Objects are fanned out by the first byte of the hash to keep directories small.
+func contentKey(sum string) string {
+	return path.Join(contentPrefix, sum[:2], sum)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// findObject returns the tracked object with checksum sum, or nil.
+func findObject(db *gorm.DB, sum string) (*types.BlobObject, error) {
+	var obj types.BlobObject
+	if err := db.Where("checksum = ?", sum).Limit(1).Find(&obj).Error; err != nil {
+		return nil, err
+	}
+	if obj.Checksum == "" {
+		return nil, nil
+	}
+	return &obj, nil
+}
+
+// writeObject writes r to its content address and verifies the checksum.
+func writeObject(ctx context.Context, store blob.Store, sum string, r io.Reader, contentType string) (*types.BlobObject, error) {
+	info, err := store.Put(ctx, contentKey(sum), r, contentType)
+	if err != nil {
+		return nil, err
+	}
+	if info.Checksum != sum {
+		_ = store.Delete(ctx, info.Key)
+		return nil, fmt.Errorf("checksum mismatch for %s: got %s", sum, info.Checksum)
+	}
+	return &types.BlobObject{Checksum: sum, StorageKey: info.Key, Size: info.Size}, nil
+}
+
+func createObject(db *gorm.DB, obj *types.BlobObject) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(obj).Error
+}
+
+// storeContent makes sure a payload with checksum sum exists in the store,
+// writing r only if no identical object is tracked yet. New objects start
+// with a zero RefCount; callers refresh it with refreshRefs once their
+// references are committed.
+func storeContent(ctx context.Context, db *gorm.DB, store blob.Store, sum string, r io.Reader, contentType string) (*types.BlobObject, error) {
+	obj, err := findObject(db, sum)
+	if err != nil || obj != nil {
+		return obj, err
+	}
+	if obj, err = writeObject(ctx, store, sum, r, contentType); err != nil {
+		return nil, err
+	}
+	return obj, createObject(db, obj)
+}
+
+// putImages writes any new thumbnail and blob payloads in imgs (in memory or
+// streamed from Source) to the blob store, reusing existing objects with the
+// same content, and records the resulting keys, checksums and sizes on the
+// records. It returns every checksum the images reference so the caller can
+// refresh their reference counts once the surrounding transaction has finished.
+//
+// The caller must hold blobMu but not mu.
+func (s *sqliteDB) putImages(imgs []types.Image) ([]string, error) {
+	var touched []string
+	put := func(sum string, r io.Reader, contentType string) (*types.BlobObject, error) {
+		// blobMu keeps the object from being released between the lookup and
+		// the insert, so the slow store write can run without holding mu.
+		s.mu.RLock()
+		obj, err := findObject(s.db, sum)
+		s.mu.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			if obj, err = writeObject(s.ctx, s.store, sum, r, contentType); err != nil {
+				return nil, err
+			}
+			s.mu.Lock()
+			err = createObject(s.db, obj)
+			s.mu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+		}
+		touched = append(touched, sum)
+		return obj, nil
+	}
+	fail := func(err error) ([]string, error) {
+		s.mu.Lock()
+		s.release(touched)
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	for i := range imgs {
+		img := &imgs[i]
+		if len(img.Thumbnail) > 0 && img.ThumbnailKey == "" {
+			obj, err := put(checksum(img.Thumbnail), bytes.NewReader(img.Thumbnail), utils.ContentType(img.Thumbnail))
+			if err != nil {
+				return fail(fmt.Errorf("store thumbnail: %w", err))
+			}
+			img.ThumbnailKey = obj.StorageKey
+			img.ThumbnailChecksum = obj.Checksum
+		} else if img.ThumbnailChecksum != "" {
+			touched = append(touched, img.ThumbnailChecksum)
+		}
+		for j := range img.Blobs {
+			b := &img.Blobs[j]
+			var sum string
+			var r io.Reader
+			switch {
+			case b.StorageKey != "":
+				if b.Checksum != "" {
+					touched = append(touched, b.Checksum)
+				}
+				continue
+			case b.Source != nil:
+				if b.Checksum == "" {
+					return fail(fmt.Errorf("store media: streamed blob %q has no checksum", b.Filename))
+				}
+				sum, r = b.Checksum, b.Source
+			case b.Data != nil:
+				sum, r = checksum(b.Data), bytes.NewReader(b.Data)
+			default:
+				continue
+			}
+			obj, err := put(sum, r, b.GetContentType())
+			if err != nil {
+				return fail(fmt.Errorf("store media: %w", err))
+			}
+			b.StorageKey = obj.StorageKey
+			b.Checksum = obj.Checksum
+			b.Size = obj.Size
+		}
+	}
+	return touched, nil
+}
+
+// postChecksums returns every blob checksum referenced by the images of a
+// post, including soft-deleted rows.
+func postChecksums(tx *gorm.DB, postID uint) ([]string, error) {
+	imageIDs := tx.Table("images").Select("id").Where("post_id = ?", postID)
+
+	var sums []string
+	if err := tx.Table("images").
+		Where("post_id = ? AND thumbnail_checksum <> ''", postID).
+		Pluck("thumbnail_checksum", &sums).Error; err != nil {
+		return nil, err
+	}
+
+	var blobSums []string
+	if err := tx.Table("image_blobs").
+		Where("image_id IN (?) AND checksum <> ''", imageIDs).
+		Pluck("checksum", &blobSums).Error; err != nil {
+		return nil, err
+	}
+	return append(sums, blobSums...), nil
+}
+
+// refreshRefs recomputes RefCount for each checksum from the image and blob
+// rows that point at it, trashed ones included. Objects left without references are removed
+// from blob_objects and their storage keys returned so the caller can delete
+// the payloads after the transaction commits.
+func refreshRefs(tx *gorm.DB, sums []string) ([]string, error) {
+	var freed []string
+	seen := make(map[string]struct{}, len(sums))
+	for _, sum := range sums {
+		if _, ok := seen[sum]; ok || sum == "" {
+			continue
+		}
+		seen[sum] = struct{}{}
+
+		var blobs, thumbs int64
+		if err := tx.Unscoped().Model(&types.ImageBlob{}).Where("checksum = ?", sum).Count(&blobs).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Unscoped().Model(&types.Image{}).Where("thumbnail_checksum = ?", sum).Count(&thumbs).Error; err != nil {
+			return nil, err
+		}
+
+		if refs := blobs + thumbs; refs > 0 {
+			if err := tx.Model(&types.BlobObject{}).Where("checksum = ?", sum).Update("ref_count", refs).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var obj types.BlobObject
+		if err := tx.Where("checksum = ?", sum).Limit(1).Find(&obj).Error; err != nil {
+			return nil, err
+		}
+		if obj.Checksum == "" {
+			continue
+		}
+		if err := tx.Delete(&obj).Error; err != nil {
+			return nil, err
+		}
+		freed = append(freed, obj.StorageKey)
+	}
+	return freed, nil
+}
+
+// release refreshes the reference counts of sums and deletes payloads that
+// are no longer referenced. The caller must hold mu.
+func (s *sqliteDB) release(sums []string) {
+	if len(sums) == 0 {
+		return
+	}
+	var freed []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		freed, err = refreshRefs(tx, sums)
+		return err
+	})
+	if err != nil {
+		log.Warn("Failed to refresh blob references", "error", err)
+		return
+	}
+	s.deleteKeys(freed)
+}
+
+// deleteKeys removes keys from the blob store. Failures only leave orphaned
+// objects behind, so they are logged rather than returned.
+func (s *sqliteDB) deleteKeys(keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(s.ctx, key); err != nil {
+			log.Warn("Failed to delete blob", "key", key, "error", err)
+		}
+	}
+}
+
+// loadBlob reads the payload of b from the blob store into b.Data.
+func (s *sqliteDB) loadBlob(b *types.ImageBlob) error {
+	if b.StorageKey == "" {
+		return nil
+	}
+	data, err := s.store.Get(s.ctx, b.StorageKey)
+	if err != nil {
+		return fmt.Errorf("load blob %d: %w", b.ID, err)
+	}
+	b.Data = data
+	return nil
+}
+
+// loadImages fills in thumbnail and blob payloads for every image in imgs.
+func (s *sqliteDB) loadImages(imgs []types.Image) error {
+	for i := range imgs {
+		img := &imgs[i]
+		if img.ThumbnailKey != "" {
+			thumb, err := s.store.Get(s.ctx, img.ThumbnailKey)
+			if err != nil {
+				return fmt.Errorf("load thumbnail for image %d: %w", img.ID, err)
+			}
+			img.Thumbnail = thumb
+		}
+		for j := range img.Blobs {
+			if err := s.loadBlob(&img.Blobs[j]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateLegacyBlobs moves payloads still held in the old image_blobs.data and
+// images.thumbnail columns into the blob store, re-keys objects written before
+// content addressing, and compacts the database file if columns were dropped.
+func migrateLegacyBlobs(ctx context.Context, db *gorm.DB, store blob.Store) error {
+	migrator := db.Migrator()
+	var touched []string
+	dropped := false
+
+	if migrator.HasColumn(&types.ImageBlob{}, "data") {
+		sums, err := moveColumn(ctx, db, store, "image_blobs", "data", "storage_key", "checksum", true)
+		if err != nil {
+			return err
+		}
+		touched = append(touched, sums...)
+		dropped = true
+	}
+	if migrator.HasColumn(&types.Image{}, "thumbnail") {
+		sums, err := moveColumn(ctx, db, store, "images", "thumbnail", "thumbnail_key", "thumbnail_checksum", false)
+		if err != nil {
+			return err
+		}
+		touched = append(touched, sums...)
+		dropped = true
+	}
+
+	for _, cols := range [][2]string{{"image_blobs", "storage_key"}, {"images", "thumbnail_key"}} {
+		sumCol := "checksum"
+		if cols[0] == "images" {
+			sumCol = "thumbnail_checksum"
+		}
+		sums, err := rekey(ctx, db, store, cols[0], cols[1], sumCol)
+		if err != nil {
+			return err
+		}
+		touched = append(touched, sums...)
+	}
+
+	if len(touched) > 0 {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			_, err := refreshRefs(tx, touched)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	if dropped {
+		log.Info("Compacting database after moving media to the blob store")
+		if err := db.Exec("VACUUM").Error; err != nil {
+			log.Warn("Failed to vacuum database", "error", err)
+		}
+	}
+	return nil
+}
+
+// sniffContentTypes fills in the content type of media stored without one,
+// as legacy rows were, from the first bytes of its payload.
+func sniffContentTypes(ctx context.Context, db *gorm.DB, store blob.Store) error {
+	var blobs []types.ImageBlob
+	if err := db.Unscoped().Select("id", "storage_key").
+		Where("(content_type IS NULL OR content_type = '') AND storage_key <> ''").
+		Find(&blobs).Error; err != nil {
+		return err
+	}
+	for _, b := range blobs {
+		r, err := store.Stream(ctx, b.StorageKey, 0, 512)
+		if err != nil {
+			log.Warn("Failed to read blob", "id", b.ID, "key", b.StorageKey, "error", err)
+			continue
+		}
+		head, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			log.Warn("Failed to read blob", "id", b.ID, "key", b.StorageKey, "error", err)
+			continue
+		}
+		if err := db.Unscoped().Model(&types.ImageBlob{}).Where("id = ?", b.ID).
+			UpdateColumn("content_type", utils.ContentType(head)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveColumn copies every payload in table.dataCol into the blob store,
+// records its key and checksum, and drops the column.
+func moveColumn(ctx context.Context, db *gorm.DB, store blob.Store, table, dataCol, keyCol, sumCol string, hasSize bool) ([]string, error) {
+	var ids []uint
+	if err := db.Table(table).
+		Where(fmt.Sprintf("(%s IS NULL OR %s = '') AND length(%s) > 0", keyCol, keyCol, dataCol)).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		log.Info("Moving media out of the database", "table", table, "count", len(ids))
+	}
+
+	sums := make([]string, 0, len(ids))
+	for _, id := range ids {
+		var data []byte
+		if err := db.Table(table).Select(dataCol).Where("id = ?", id).Row().Scan(&data); err != nil {
+			return nil, err
+		}
+		sum := checksum(data)
+		obj, err := storeContent(ctx, db, store, sum, bytes.NewReader(data), utils.ContentType(data))
+		if err != nil {
+			return nil, fmt.Errorf("move %s %d: %w", table, id, err)
+		}
+		updates := map[string]any{keyCol: obj.StorageKey, sumCol: sum}
+		if hasSize {
+			updates["size"] = obj.Size
+		}
+		if err := db.Table(table).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		sums = append(sums, sum)
+	}
+
+	if err := db.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, dataCol)).Error; err != nil {
+		return nil, fmt.Errorf("drop %s.%s: %w", table, dataCol, err)
+	}
+	return sums, nil
+}
+
+// rekey moves objects stored under per-upload keys to their content address,
+// sharing one object between identical payloads.
+func rekey(ctx context.Context, db *gorm.DB, store blob.Store, table, keyCol, sumCol string) ([]string, error) {
+	var rows []struct {
+		ID       uint
+		OldKey   string
+		Checksum string
+	}
+	if err := db.Table(table).
+		Select(fmt.Sprintf("id, %s AS old_key, %s AS checksum", keyCol, sumCol)).
+		Where(fmt.Sprintf("%s <> '' AND %s NOT LIKE ?", keyCol, keyCol), contentPrefix+"/%").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 {
+		log.Info("Re-keying media by content hash", "table", table, "count", len(rows))
+	}
+
+	sums := make([]string, 0, len(rows))
+	for _, row := range rows {
+		sum := row.Checksum
+		var rc io.ReadCloser
+		if sum == "" {
+			data, err := store.Get(ctx, row.OldKey)
+			if err != nil {
+				return nil, fmt.Errorf("read %s: %w", row.OldKey, err)
+			}
+			sum, rc = checksum(data), io.NopCloser(bytes.NewReader(data))
+		} else {
+			var err error
+			if rc, err = store.Stream(ctx, row.OldKey, 0, -1); err != nil {
+				return nil, fmt.Errorf("read %s: %w", row.OldKey, err)
+			}
+		}
+
+		obj, err := storeContent(ctx, db, store, sum, rc, "")
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("re-key %s: %w", row.OldKey, err)
+		}
+		if err := db.Table(table).Where("id = ?", row.ID).Updates(map[string]any{keyCol: obj.StorageKey, sumCol: sum}).Error; err != nil {
+			return nil, err
+		}
+		if err := store.Delete(ctx, row.OldKey); err != nil {
+			log.Warn("Failed to delete re-keyed blob", "key", row.OldKey, "error", err)
+		}
+		sums = append(sums, sum)
+	}
+	return sums, nil
+}
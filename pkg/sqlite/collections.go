@@ -0,0 +1,303 @@
+package sqlite
+
+import (
+	"errors"
+	"slices"
+
+	"gorm.io/gorm"
+
+	"drigo/pkg/types"
+)
+
+// liveItems returns the items of the given collections whose posts still
+// exist and are published and unarchived, ordered by collection and position.
+func liveItems(db *gorm.DB, collectionIDs []uint) ([]types.CollectionItem, error) {
+	var items []types.CollectionItem
+	err := db.Model(&types.CollectionItem{}).
+		Joins("JOIN posts p ON p.id = collection_items.post_id AND p.deleted_at IS NULL AND p.published_at IS NOT NULL AND p.archived_at IS NULL").
+		Where("collection_items.collection_id IN ?", collectionIDs).
+		Order("collection_items.collection_id, collection_items.position").
+		Find(&items).Error
+	return items, err
+}
+
+// loadCollectionPosts fills Posts in membership order and derives AllowedRoles
+// for collections that don't set their own.
+func loadCollectionPosts(db *gorm.DB, collections []*types.Collection) error {
+	if len(collections) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(collections))
+	for i, c := range collections {
+		ids[i] = c.ID
+	}
+	items, err := liveItems(db, ids)
+	if err != nil {
+		return err
+	}
+
+	postIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		postIDs = append(postIDs, item.PostID)
+	}
+	var posts []*types.Post
+	if len(postIDs) > 0 {
+		if err := listPreloads(db).Where("id IN ?", postIDs).Find(&posts).Error; err != nil {
+			return err
+		}
+	}
+	byID := make(map[uint]*types.Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+
+	byCollection := make(map[uint]*types.Collection, len(collections))
+	for _, c := range collections {
+		c.Posts = []*types.Post{}
+		byCollection[c.ID] = c
+	}
+	for _, item := range items {
+		if p, ok := byID[item.PostID]; ok {
+			c := byCollection[item.CollectionID]
+			c.Posts = append(c.Posts, p)
+		}
+	}
+
+	var tiers []types.Tier
+	if err := db.Find(&tiers).Error; err != nil {
+		return err
+	}
+	tierRoles := make(map[uint]string, len(tiers))
+	for _, t := range tiers {
+		tierRoles[t.ID] = t.RoleID
+	}
+	for _, c := range collections {
+		if len(c.AllowedRoles) == 0 {
+			c.AllowedRoles = unionRoles(c.Posts, tierRoles)
+			c.InheritedRoles = true
+		}
+	}
+	return nil
+}
+
+// unionRoles returns every role that opens at least one of posts, counting
+// the role of a post's minimum tier, found in tierRoles by tier ID. If any
+// post is unrestricted the result is empty, since anyone can view part of the
+// set.
+func unionRoles(posts []*types.Post, tierRoles map[uint]string) []types.Allowed {
+	var roles []types.Allowed
+	seen := make(map[string]bool)
+	for _, p := range posts {
+		allowed := p.AllowedRoles
+		if p.MinTierID != nil && tierRoles[*p.MinTierID] != "" {
+			allowed = append(slices.Clip(allowed), types.Allowed{RoleID: tierRoles[*p.MinTierID]})
+		}
+		if len(allowed) == 0 {
+			return nil
+		}
+		for _, r := range allowed {
+			if !seen[r.RoleID] {
+				seen[r.RoleID] = true
+				roles = append(roles, r)
+			}
+		}
+	}
+	return roles
+}
+
+// setCollectionItems replaces the membership of collection id with postIDs,
+// in order. Duplicate IDs keep their first position.
+func setCollectionItems(tx *gorm.DB, id uint, postIDs []uint) error {
+	if err := tx.Where("collection_id = ?", id).Delete(&types.CollectionItem{}).Error; err != nil {
+		return err
+	}
+	items := make([]types.CollectionItem, 0, len(postIDs))
+	seen := make(map[uint]bool, len(postIDs))
+	for _, postID := range postIDs {
+		if postID == 0 || seen[postID] {
+			continue
+		}
+		seen[postID] = true
+		items = append(items, types.CollectionItem{CollectionID: id, PostID: postID, Position: len(items)})
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return tx.Create(&items).Error
+}
+
+// ListCollections returns every collection, newest first, with its posts.
+func (s *sqliteDB) ListCollections() ([]*types.Collection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var collections []*types.Collection
+	if err := s.db.Preload("AllowedRoles").Order("id desc").Find(&collections).Error; err != nil {
+		return nil, err
+	}
+	if err := loadCollectionPosts(s.db, collections); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// ReadCollection fetches a collection by PK with its posts in order.
+func (s *sqliteDB) ReadCollection(id uint) (*types.Collection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var c types.Collection
+	if err := s.db.Preload("AllowedRoles").First(&c, id).Error; err != nil {
+		return nil, err
+	}
+	if err := loadCollectionPosts(s.db, []*types.Collection{&c}); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateCollection inserts c with its AllowedRoles and the posts postIDs, in order.
+func (s *sqliteDB) CreateCollection(c *types.Collection, postIDs []uint) error {
+	if c == nil {
+		return errors.New("nil collection")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("AllowedRoles", "Items").Create(c).Error; err != nil {
+			return err
+		}
+		if len(c.AllowedRoles) > 0 {
+			roles, err := resolveAllowedRoles(tx, c.AllowedRoles)
+			if err != nil {
+				return err
+			}
+			c.AllowedRoles = roles
+			if err := tx.Model(c).Association("AllowedRoles").Replace(roles); err != nil {
+				return err
+			}
+		}
+		return setCollectionItems(tx, c.ID, postIDs)
+	})
+}
+
+// UpdateCollection saves the title, description and AllowedRoles of c.
+// Membership is left alone; use SetCollectionPosts for that.
+func (s *sqliteDB) UpdateCollection(c *types.Collection) error {
+	if c == nil || c.ID == 0 {
+		return errors.New("invalid collection (nil or no ID)")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("AllowedRoles", "Items").Save(c).Error; err != nil {
+			return err
+		}
+		roles, err := resolveAllowedRoles(tx, c.AllowedRoles)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(c).Association("AllowedRoles").Clear(); err != nil {
+			return err
+		}
+		c.AllowedRoles = roles
+		if len(roles) == 0 {
+			return nil
+		}
+		return tx.Model(c).Association("AllowedRoles").Replace(roles)
+	})
+}
+
+// SetCollectionPosts replaces the posts of collection id with postIDs, in order.
+func (s *sqliteDB) SetCollectionPosts(id uint, postIDs []uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&types.Collection{}, id).Error; err != nil {
+			return err
+		}
+		return setCollectionItems(tx, id, postIDs)
+	})
+}
+
+// DeleteCollection removes a collection and its membership. Posts are kept.
+func (s *sqliteDB) DeleteCollection(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var c types.Collection
+		if err := tx.First(&c, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&c).Association("AllowedRoles").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Where("collection_id = ?", id).Delete(&types.CollectionItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&c).Error
+	})
+}
+
+// PostCollections returns where post id sits in each collection containing
+// it, with links to its neighbours among live posts.
+func (s *sqliteDB) PostCollections(id uint) ([]types.CollectionNav, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var collections []types.Collection
+	err := s.db.
+		Where("id IN (?)", s.db.Model(&types.CollectionItem{}).Select("collection_id").Where("post_id = ?", id)).
+		Order("id").
+		Find(&collections).Error
+	if err != nil || len(collections) == 0 {
+		return nil, err
+	}
+
+	ids := make([]uint, len(collections))
+	for i, c := range collections {
+		ids[i] = c.ID
+	}
+	items, err := liveItems(s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	var refs []struct {
+		ID      uint
+		PostKey string
+		Title   string
+	}
+	postIDs := make([]uint, len(items))
+	for i, item := range items {
+		postIDs[i] = item.PostID
+	}
+	if err := s.db.Model(&types.Post{}).Select("id", "post_key", "title").Where("id IN ?", postIDs).Scan(&refs).Error; err != nil {
+		return nil, err
+	}
+	refByID := make(map[uint]*types.PostRef, len(refs))
+	for _, r := range refs {
+		refByID[r.ID] = &types.PostRef{PostKey: r.PostKey, Title: r.Title}
+	}
+
+	members := make(map[uint][]uint, len(collections))
+	for _, item := range items {
+		members[item.CollectionID] = append(members[item.CollectionID], item.PostID)
+	}
+
+	var navs []types.CollectionNav
+	for _, c := range collections {
+		order := members[c.ID]
+		for i, postID := range order {
+			if postID != id {
+				continue
+			}
+			nav := types.CollectionNav{ID: c.ID, Title: c.Title, Position: i + 1, Total: len(order)}
+			if i > 0 {
+				nav.Prev = refByID[order[i-1]]
+			}
+			if i+1 < len(order) {
+				nav.Next = refByID[order[i+1]]
+			}
+			navs = append(navs, nav)
+			break
+		}
+	}
+	return navs, nil
+}
@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"drigo/pkg/types"
+)
+
+// PostGrants returns every grant on a post, including expired ones.
+func (s *sqliteDB) PostGrants(postID uint) ([]types.PostGrant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var grants []types.PostGrant
+	err := s.db.Where("post_id = ?", postID).Order("id").Find(&grants).Error
+	return grants, err
+}
+
+// ActiveGrant returns the grant a user holds on a post at now, or nil.
+func (s *sqliteDB) ActiveGrant(postID uint, userID string, now time.Time) (*types.PostGrant, error) {
+	if userID == "" {
+		return nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// Find rather than First: most lookups miss and shouldn't log as errors.
+	var grants []types.PostGrant
+	err := s.db.Where("post_id = ? AND user_id = ?", postID, userID).
+		Where("expires_at IS NULL OR expires_at > ?", now.UTC()).
+		Limit(1).
+		Find(&grants).Error
+	if err != nil || len(grants) == 0 {
+		return nil, err
+	}
+	return &grants[0], nil
+}
+
+// SetPostGrant creates or replaces the grant of g.UserID on g.PostID.
+func (s *sqliteDB) SetPostGrant(g *types.PostGrant) error {
+	if g == nil || g.PostID == 0 || g.UserID == "" || (g.Effect != types.GrantAllow && g.Effect != types.GrantDeny) {
+		return errors.New("invalid post grant")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"effect", "expires_at", "granted_by", "updated_at"}),
+	}).Create(g).Error
+	if err != nil {
+		return err
+	}
+	return s.db.Where("post_id = ? AND user_id = ?", g.PostID, g.UserID).First(g).Error
+}
+
+// DeletePostGrant removes a user's grant on a post.
+func (s *sqliteDB) DeletePostGrant(postID uint, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.db.Where("post_id = ? AND user_id = ?", postID, userID).Delete(&types.PostGrant{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
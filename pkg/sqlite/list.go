@@ -1,37 +1,106 @@
 package sqlite
 
 import (
+	"strings"
+	"time"
+
 	"drigo/pkg/types"
 
 	"gorm.io/gorm"
 )
 
-// ListPosts returns a list of posts. sort can be "date" (by timestamp) or "id" (by insertion, default).
-func (s *sqliteDB) ListPosts(limit, offset int, sort string) ([]*types.Post, error) {
+// ListOptions controls ListPosts.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// Sort can be "date" (by timestamp) or "id" (by insertion, default).
+	Sort string
+
+	// Tags filters to posts with these tags, matched by slug. TagMode "all"
+	// requires every tag; anything else requires at least one.
+	Tags    []string
+	TagMode string
+
+	// After and Before, when set, bound the post timestamp: After inclusive,
+	// Before exclusive.
+	After  time.Time
+	Before time.Time
+
+	// Viewable limits the list to posts UserID holding RoleIDs may view.
+	Viewable bool
+	UserID   string
+	RoleIDs  []string
+}
+
+// ListPosts returns a list of published, unarchived posts.
+func (s *sqliteDB) ListPosts(opts ListOptions) ([]*types.Post, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var posts []*types.Post
 
 	orderClause := "id desc" // default: newest by insertion
-	if sort == "date" {
+	if opts.Sort == "date" {
 		orderClause = "timestamp desc"
 	}
 
-	err := s.db.
+	q := listPreloads(s.db).Where("published_at IS NOT NULL AND archived_at IS NULL")
+	if slugs := tagSlugs(opts.Tags); len(slugs) > 0 {
+		tagged := s.db.Table("post_tags AS pt").Select("pt.post_id").
+			Joins("JOIN tags t ON t.id = pt.tag_id").
+			Where("t.slug IN ?", slugs)
+		if opts.TagMode == "all" {
+			tagged = tagged.Group("pt.post_id").Having("COUNT(DISTINCT t.id) = ?", len(slugs))
+		}
+		q = q.Where("id IN (?)", tagged)
+	}
+	if !opts.After.IsZero() {
+		q = q.Where("timestamp >= ?", opts.After.UTC())
+	}
+	if !opts.Before.IsZero() {
+		q = q.Where("timestamp < ?", opts.Before.UTC())
+	}
+	if opts.Viewable {
+		query, args := s.viewableClause("posts", opts.UserID, opts.RoleIDs)
+		q = q.Where(query, args...)
+	}
+
+	err := q.
+		Order(orderClause).
+		Limit(opts.Limit).
+		Offset(opts.Offset).
+		Find(&posts).Error
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// listPreloads loads the associations gallery listings need, without payloads.
+func listPreloads(db *gorm.DB) *gorm.DB {
+	return db.
 		Preload("Author").
 		Preload("Images", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id", "created_at", "updated_at", "deleted_at", "post_id", "(CASE WHEN length(thumbnail) > 0 THEN 1 ELSE 0 END) as has_thumbnail")
+			return db.Select("id", "created_at", "updated_at", "deleted_at", "post_id", "thumbnail_key", "thumbnail_checksum", "thumbnail_blurred", "(CASE WHEN thumbnail_key <> '' THEN 1 ELSE 0 END) as has_thumbnail")
 		}).
 		Preload("Images.Blobs", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id", "created_at", "updated_at", "deleted_at", "image_id", "index", "content_type", "filename", "size")
+			return db.Select("id", "created_at", "updated_at", "deleted_at", "image_id", "index", "storage_key", "checksum", "content_type", "filename", "size")
 		}).
 		Preload("AllowedRoles").
-		Order(orderClause).
+		Preload("Tags")
+}
+
+// PostTitles returns up to limit posts, drafts and scheduled ones included,
+// whose title contains query or whose key equals it, newest first.
+func (s *sqliteDB) PostTitles(query string, limit int) ([]types.PostRef, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%"
+	var refs []types.PostRef
+	err := s.db.Model(&types.Post{}).
+		Select("post_key", "title").
+		Where(`title LIKE ? ESCAPE '\' OR post_key = ?`, pattern, query).
+		Order("id desc").
 		Limit(limit).
-		Offset(offset).
-		Find(&posts).Error
-	if err != nil {
-		return nil, err
-	}
-	return posts, nil
+		Scan(&refs).Error
+	return refs, err
 }
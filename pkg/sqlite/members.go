@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"drigo/pkg/types"
+)
+
+// RecordDownload remembers that a user fetched the full media of a post.
+func (s *sqliteDB) RecordDownload(userID string, postID uint) error {
+	if userID == "" || postID == 0 {
+		return errors.New("invalid download")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "post_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at"}),
+	}).Create(&types.Download{UserID: userID, PostID: postID}).Error
+}
+
+// DownloadedPosts returns the posts a user has downloaded with their allowed
+// roles, newest first.
+func (s *sqliteDB) DownloadedPosts(userID string) ([]*types.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var posts []*types.Post
+	err := s.db.Preload("AllowedRoles").
+		Joins("JOIN downloads d ON d.post_id = posts.id").
+		Where("d.user_id = ?", userID).
+		Order("posts.id desc").
+		Find(&posts).Error
+	return posts, err
+}
+
+// MarkRolesChanged records that a user lost roles at the given time. Users
+// who never signed in have no sessions to invalidate and are left alone.
+func (s *sqliteDB) MarkRolesChanged(userID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Model(&types.User{}).Where("user_id = ?", userID).Update("roles_changed_at", at.UTC()).Error
+}
+
+// AddAuditEntry appends an entry to the audit log.
+func (s *sqliteDB) AddAuditEntry(e *types.AuditEntry) error {
+	if e == nil || e.Action == "" {
+		return errors.New("invalid audit entry")
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Create(e).Error
+}
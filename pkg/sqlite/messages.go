@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"drigo/pkg/types"
+)
+
+// AddPostMessage records a Discord message announcing a post.
+func (s *sqliteDB) AddPostMessage(m *types.PostMessage) error {
+	if m == nil || m.PostID == 0 || m.MessageID == "" {
+		return errors.New("invalid post message")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(m).Error
+}
+
+// PostMessages returns the Discord messages announcing a post.
+func (s *sqliteDB) PostMessages(postID uint) ([]types.PostMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var msgs []types.PostMessage
+	err := s.db.Where("post_id = ?", postID).Order("id").Find(&msgs).Error
+	return msgs, err
+}
+
+// PostKeyByMessageID returns the key of the post a Discord message announces,
+// or "" if the message isn't recorded.
+func (s *sqliteDB) PostKeyByMessageID(messageID string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var key string
+	err := s.db.Model(&types.Post{}).
+		Select("posts.post_key").
+		Joins("JOIN post_messages pm ON pm.post_id = posts.id").
+		Where("pm.message_id = ?", messageID).
+		Limit(1).
+		Scan(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return key, err
+}
+
+// DeletePostMessage forgets one recorded message, e.g. after it was deleted in Discord.
+func (s *sqliteDB) DeletePostMessage(messageID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Where("message_id = ?", messageID).Delete(&types.PostMessage{}).Error
+}
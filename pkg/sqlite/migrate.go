@@ -0,0 +1,531 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gorm.io/gorm"
+
+	"drigo/pkg/blob"
+	"drigo/pkg/types"
+)
+
+// Migration is one numbered schema or data change. Migrations run in Version
+// order and each is recorded in schema_migrations once applied. They must also
+// tolerate databases created by AutoMigrate before versioning existed, since
+// those start with an empty schema_migrations table.
+type Migration struct {
+	Version int
+	Name    string
+
+	up func(ctx context.Context, db *gorm.DB, store blob.Store) error
+	// noTx runs the migration outside a transaction, for steps that write to
+	// the blob store or VACUUM. They must be safe to re-run after a failure.
+	noTx bool
+	// reindex rebuilds posts_fts once all pending migrations have run, for
+	// steps that change what postDocumentSQL reads.
+	reindex bool
+}
+
+// schemaMigration records an applied Migration.
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// migrations is the full history. Append new entries; never edit or reorder
+// ones that have shipped. Schema changes declare the models as they stood at
+// that version instead of using pkg/types, so later changes to the live
+// models can't alter what an old migration creates.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline_schema",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			return baselineSchema(db)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "blob_store",
+		noTx:    true,
+		up: func(ctx context.Context, db *gorm.DB, store blob.Store) error {
+			type BlobObject struct {
+				Checksum   string `gorm:"primaryKey"`
+				StorageKey string
+				Size       int64
+				RefCount   int64
+				CreatedAt  time.Time
+			}
+			type Image struct {
+				ID                uint
+				ThumbnailKey      string `gorm:"index"`
+				ThumbnailChecksum string `gorm:"index"`
+			}
+			type ImageBlob struct {
+				ID         uint
+				StorageKey string `gorm:"index"`
+				Checksum   string `gorm:"index"`
+			}
+			if err := db.AutoMigrate(&BlobObject{}, &Image{}, &ImageBlob{}); err != nil {
+				return err
+			}
+			return migrateLegacyBlobs(ctx, db, store)
+		},
+	},
+	{
+		Version: 3,
+		Name:    "resumable_uploads",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type Upload struct {
+				ID          string `gorm:"primaryKey"`
+				UserID      string `gorm:"index"`
+				Filename    string
+				ContentType string
+				Length      int64
+				Offset      int64 `gorm:"column:upload_offset"`
+				Checksum    string
+				HashState   []byte
+				CreatedAt   time.Time
+				UpdatedAt   time.Time
+				ExpiresAt   time.Time `gorm:"index"`
+			}
+			return db.AutoMigrate(&Upload{})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "backfill_blob_sizes",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			return db.Exec(`UPDATE image_blobs SET size = (
+				SELECT blob_objects.size FROM blob_objects WHERE blob_objects.checksum = image_blobs.checksum
+			) WHERE (size IS NULL OR size = 0) AND checksum <> ''
+			AND EXISTS (SELECT 1 FROM blob_objects WHERE blob_objects.checksum = image_blobs.checksum)`).Error
+		},
+	},
+	{
+		Version: 5,
+		Name:    "posts_fts",
+		reindex: true,
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			return db.Exec(createPostsFTS).Error
+		},
+	},
+	{
+		Version: 6,
+		Name:    "tags",
+		reindex: true,
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type Tag struct {
+				ID        uint   `gorm:"primarykey"`
+				Name      string `gorm:"size:64"`
+				Slug      string `gorm:"uniqueIndex;size:64"`
+				CreatedAt time.Time
+				UpdatedAt time.Time
+			}
+			type Post struct {
+				ID   uint
+				Tags []Tag `gorm:"many2many:post_tags"`
+			}
+			return db.AutoMigrate(&Tag{}, &Post{})
+		},
+	},
+	{
+		Version: 7,
+		Name:    "collections",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type Allowed struct {
+				ID uint
+			}
+			type CollectionItem struct {
+				ID           uint `gorm:"primarykey"`
+				CollectionID uint `gorm:"uniqueIndex:idx_collection_post"`
+				PostID       uint `gorm:"uniqueIndex:idx_collection_post;index"`
+				Position     int
+			}
+			type Collection struct {
+				gorm.Model
+				Title        string           `gorm:"type:text"`
+				Description  string           `gorm:"type:text"`
+				AllowedRoles []Allowed        `gorm:"many2many:collection_allowed_roles"`
+				Items        []CollectionItem `gorm:"constraint:OnDelete:CASCADE"`
+			}
+			return db.AutoMigrate(&Collection{}, &CollectionItem{})
+		},
+	},
+	{
+		Version: 8,
+		Name:    "scheduled_publishing",
+		reindex: true,
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type Post struct {
+				ID          uint
+				PublishAt   *time.Time `gorm:"index"`
+				PublishedAt *time.Time `gorm:"index"`
+			}
+			if err := db.AutoMigrate(&Post{}); err != nil {
+				return err
+			}
+			// Everything that existed before scheduling went out on creation.
+			return db.Exec("UPDATE posts SET published_at = created_at WHERE published_at IS NULL AND publish_at IS NULL").Error
+		},
+	},
+	{
+		Version: 9,
+		Name:    "drafts",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type Post struct {
+				ID    uint
+				Draft bool `gorm:"index"`
+			}
+			if err := db.AutoMigrate(&Post{}); err != nil {
+				return err
+			}
+			// Posts whose schedule was cancelled were drafts in all but name.
+			return db.Exec("UPDATE posts SET draft = true WHERE published_at IS NULL AND publish_at IS NULL").Error
+		},
+	},
+	{
+		Version: 10,
+		Name:    "post_messages",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type PostMessage struct {
+				ID        uint   `gorm:"primarykey"`
+				PostID    uint   `gorm:"index"`
+				ChannelID string `gorm:"size:32"`
+				MessageID string `gorm:"uniqueIndex;size:32"`
+				CreatedAt time.Time
+			}
+			return db.AutoMigrate(&PostMessage{})
+		},
+	},
+	{
+		Version: 11,
+		Name:    "pending_posts",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type PendingMedia struct {
+				ID          uint   `gorm:"primarykey"`
+				PostKey     string `gorm:"index;size:32"`
+				Index       int
+				Filename    string
+				ContentType string
+				Size        int64
+				StorageKey  string
+			}
+			type PendingPost struct {
+				PostKey        string `gorm:"primaryKey;size:32"`
+				GuildID        string
+				ChannelID      string
+				Author         string // JSON-encoded discordgo.User
+				Title          string
+				Description    string
+				NeedsThumbnail bool
+				S3ThumbURL     string
+				ThumbnailKey   string
+				Media          []PendingMedia `gorm:"foreignKey:PostKey;references:PostKey"`
+				CreatedAt      time.Time
+				ExpiresAt      time.Time `gorm:"index"`
+			}
+			return db.AutoMigrate(&PendingPost{}, &PendingMedia{})
+		},
+	},
+	{
+		Version: 12,
+		Name:    "imported_pending_posts",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type PendingPost struct {
+				PostKey  string `gorm:"primaryKey;size:32"`
+				PostedAt time.Time
+			}
+			type PendingMedia struct {
+				ID        uint
+				SourceURL string
+			}
+			return db.AutoMigrate(&PendingPost{}, &PendingMedia{})
+		},
+	},
+	{
+		Version: 13,
+		Name:    "member_access",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type User struct {
+				ID             uint
+				RolesChangedAt *time.Time
+			}
+			type Download struct {
+				ID        uint   `gorm:"primarykey"`
+				UserID    string `gorm:"uniqueIndex:idx_download_user_post;size:32"`
+				PostID    uint   `gorm:"uniqueIndex:idx_download_user_post;index"`
+				CreatedAt time.Time
+				UpdatedAt time.Time
+			}
+			type AuditEntry struct {
+				ID         uint      `gorm:"primarykey"`
+				CreatedAt  time.Time `gorm:"index"`
+				ActorID    string    `gorm:"index;size:32"`
+				Action     string    `gorm:"index;size:64"`
+				TargetType string    `gorm:"size:32"`
+				TargetID   string    `gorm:"size:64"`
+				Detail     string
+			}
+			return db.AutoMigrate(&User{}, &Download{}, &AuditEntry{})
+		},
+	},
+	{
+		Version: 14,
+		Name:    "tiers",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type Tier struct {
+				ID        uint   `gorm:"primarykey"`
+				RoleID    string `gorm:"uniqueIndex;size:32"`
+				Name      string `gorm:"size:64"`
+				Rank      int    `gorm:"index"`
+				CreatedAt time.Time
+				UpdatedAt time.Time
+			}
+			type Post struct {
+				ID        uint
+				MinTierID *uint `gorm:"index"`
+			}
+			return db.AutoMigrate(&Tier{}, &Post{})
+		},
+	},
+	{
+		Version: 15,
+		Name:    "post_grants",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type PostGrant struct {
+				ID        uint       `gorm:"primarykey"`
+				PostID    uint       `gorm:"uniqueIndex:idx_grant_post_user"`
+				UserID    string     `gorm:"uniqueIndex:idx_grant_post_user;size:32"`
+				Effect    string     `gorm:"size:8"`
+				ExpiresAt *time.Time `gorm:"index"`
+				GrantedBy string     `gorm:"size:32"`
+				CreatedAt time.Time
+				UpdatedAt time.Time
+			}
+			return db.AutoMigrate(&PostGrant{})
+		},
+	},
+	{
+		Version: 16,
+		Name:    "share_links",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type ShareLink struct {
+				ID        uint      `gorm:"primarykey"`
+				Token     string    `gorm:"uniqueIndex;size:96"`
+				PostID    uint      `gorm:"index"`
+				PostKey   string    `gorm:"size:32"`
+				ExpiresAt time.Time `gorm:"index"`
+				MaxUses   int
+				Uses      int
+				Media     string // JSON-encoded media indices
+				CreatedBy string `gorm:"size:32"`
+				CreatedAt time.Time
+				RevokedAt *time.Time
+			}
+			return db.AutoMigrate(&ShareLink{})
+		},
+	},
+	{
+		Version: 17,
+		Name:    "public_after",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type Post struct {
+				ID             uint
+				PublicAfter    *time.Time `gorm:"index"`
+				AnnouncePublic bool
+				WentPublicAt   *time.Time
+			}
+			type Image struct {
+				ID               uint
+				ThumbnailBlurred bool
+			}
+			return db.AutoMigrate(&Post{}, &Image{})
+		},
+	},
+	{
+		Version: 18,
+		Name:    "post_expiry",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type Post struct {
+				ID                  uint
+				ExpiresAt           *time.Time `gorm:"index"`
+				ArchivedAt          *time.Time `gorm:"index"`
+				ExpireAnnouncements bool
+			}
+			return db.AutoMigrate(&Post{})
+		},
+	},
+	{
+		Version: 19,
+		Name:    "trash",
+		noTx:    true,
+		up: func(ctx context.Context, db *gorm.DB, store blob.Store) error {
+			// Posts deleted before the trash existed kept their payloads, which
+			// blob_store moved into the store, but can't be restored: their
+			// rows were left half-deleted. Purge them for good and free the
+			// payloads nothing else uses.
+			var freed []string
+			err := db.Transaction(func(tx *gorm.DB) error {
+				var ids []uint
+				if err := tx.Unscoped().Model(&types.Post{}).Where("deleted_at IS NOT NULL").Pluck("id", &ids).Error; err != nil {
+					return err
+				}
+				var sums []string
+				for _, id := range ids {
+					touched, err := purgePost(tx, id)
+					if err != nil {
+						return err
+					}
+					sums = append(sums, touched...)
+				}
+				var err error
+				freed, err = refreshRefs(tx, sums)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			// A failed delete only leaves an orphaned object behind.
+			for _, key := range freed {
+				if err := store.Delete(ctx, key); err != nil {
+					log.Warn("Failed to delete blob", "key", key, "error", err)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 20,
+		Name:    "audit_log",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type AuditEntry struct {
+				ID         uint
+				ActorName  string `gorm:"size:64"`
+				TargetType string `gorm:"index:idx_audit_target;size:32"`
+				TargetID   string `gorm:"index:idx_audit_target;size:64"`
+				Changes    string // JSON-encoded field changes
+				Source     string `gorm:"size:16"`
+				IP         string `gorm:"size:64"`
+				UserAgent  string
+				Method     string `gorm:"size:8"`
+				Path       string
+			}
+			return db.AutoMigrate(&AuditEntry{})
+		},
+	},
+	{
+		Version: 21,
+		Name:    "share_media_checksums",
+		up: func(_ context.Context, db *gorm.DB, _ blob.Store) error {
+			type ShareLink struct {
+				ID             uint
+				MediaChecksums string // JSON-encoded media checksums
+			}
+			if err := db.AutoMigrate(&ShareLink{}); err != nil {
+				return err
+			}
+			return migrateShareMedia(db)
+		},
+	},
+	{
+		Version: 22,
+		Name:    "sniff_content_types",
+		up: func(ctx context.Context, db *gorm.DB, store blob.Store) error {
+			return sniffContentTypes(ctx, db, store)
+		},
+	},
+}
+
+// pendingMigrations returns the migrations not yet recorded in db.
+func pendingMigrations(db *gorm.DB) ([]Migration, error) {
+	applied := make(map[int]bool)
+	if db.Migrator().HasTable(&schemaMigration{}) {
+		var versions []int
+		if err := db.Model(&schemaMigration{}).Pluck("version", &versions).Error; err != nil {
+			return nil, err
+		}
+		for _, v := range versions {
+			applied[v] = true
+		}
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// runMigrations applies every pending migration in order and returns the ones
+// it ran. With dryRun it only reports them.
+func runMigrations(ctx context.Context, db *gorm.DB, store blob.Store, dryRun bool) ([]Migration, error) {
+	pending, err := pendingMigrations(db)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	if dryRun || len(pending) == 0 {
+		return pending, nil
+	}
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+
+	for i, m := range pending {
+		log.Info("Applying migration", "version", m.Version, "name", m.Name)
+		record := func(tx *gorm.DB) error {
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+		}
+
+		if m.noTx {
+			err = m.up(ctx, db, store)
+			if err == nil {
+				err = record(db)
+			}
+		} else {
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := m.up(ctx, tx, store); err != nil {
+					return err
+				}
+				return record(tx)
+			})
+		}
+		if err != nil {
+			return pending[:i], fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+
+	if slices.ContainsFunc(pending, func(m Migration) bool { return m.reindex }) {
+		log.Info("Rebuilding search index")
+		if err := rebuildPostsFTS(db); err != nil {
+			return pending, fmt.Errorf("rebuild search index: %w", err)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate opens the database at path and applies pending migrations without
+// starting anything else. With dryRun it returns the pending migrations and
+// leaves the database untouched.
+func Migrate(path string, ctx context.Context, store blob.Store, dryRun bool) ([]Migration, error) {
+	if store == nil && !dryRun {
+		return nil, fmt.Errorf("blob store is required")
+	}
+	gdb, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	if sqlDB, err := gdb.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	return runMigrations(ctx, gdb.WithContext(ctx), store, dryRun)
+}
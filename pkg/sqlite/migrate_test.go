@@ -0,0 +1,241 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"drigo/pkg/blob"
+	"drigo/pkg/types"
+)
+
+// legacyFixture is a database from the oldest supported release: media lives
+// in BLOB columns and there is no schema_migrations table.
+const legacyFixture = "testdata/legacy.db"
+
+// copyFixture copies the legacy fixture into a temp dir and seeds it with a
+// post whose two images share the same payload, and a post deleted the way
+// releases before the trash left them.
+func copyFixture(t *testing.T) string {
+	t.Helper()
+
+	data, err := os.ReadFile(legacyFixture)
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "sqlite.db")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer db.Close()
+
+	seed := []string{
+		`INSERT INTO posts (id, post_key, title, timestamp) VALUES (1, 'legacy', 'Legacy post', '2024-01-01 00:00:00')`,
+		`INSERT INTO images (id, post_id, thumbnail, has_thumbnail) VALUES (1, 1, x'7468756d62', 1), (2, 1, NULL, 0)`,
+		`INSERT INTO image_blobs (id, image_id, "index", data, content_type) VALUES
+			(1, 1, 0, x'7061796c6f6164', 'image/png'),
+			(2, 2, 0, x'7061796c6f6164', NULL)`,
+		`INSERT INTO posts (id, post_key, title, timestamp, deleted_at) VALUES (2, 'deleted', 'Deleted post', '2024-01-01 00:00:00', '2024-02-01 00:00:00')`,
+		`INSERT INTO images (id, post_id, has_thumbnail, deleted_at) VALUES (3, 2, 0, '2024-02-01 00:00:00')`,
+		`INSERT INTO image_blobs (id, image_id, "index", data, content_type, deleted_at) VALUES
+			(3, 3, 0, x'6f727068616e', 'image/png', '2024-02-01 00:00:00')`,
+	}
+	for _, stmt := range seed {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("seed fixture: %v", err)
+		}
+	}
+	return path
+}
+
+func TestMigrationsAreSequential(t *testing.T) {
+	t.Parallel()
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("migration %q has version %d, want %d", m.Name, m.Version, i+1)
+		}
+		if m.Name == "" || m.up == nil {
+			t.Fatalf("migration %d is missing a name or up func", m.Version)
+		}
+	}
+}
+
+// TestMigrationsCoverModels checks that a fresh database has every table,
+// column and index the models in pkg/types expect, since migrations declare
+// their own snapshots of them.
+func TestMigrationsCoverModels(t *testing.T) {
+	t.Parallel()
+
+	store, err := blob.NewLocal(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := Connect(filepath.Join(t.TempDir(), "sqlite.db"), context.Background(), store)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Stop()
+	db := conn.(*sqliteDB).db
+	migrator := db.Migrator()
+
+	models := []any{
+		&types.User{}, &types.Allowed{}, &types.Post{}, &types.Image{}, &types.ImageBlob{},
+		&types.Settings{}, &types.CachedRole{}, &types.CachedChannel{}, &types.BlobObject{},
+		&types.Upload{}, &types.Tag{}, &types.Collection{}, &types.CollectionItem{},
+		&types.PostMessage{}, &types.PendingPost{}, &types.PendingMedia{}, &types.Download{},
+		&types.AuditEntry{}, &types.Tier{}, &types.PostGrant{}, &types.ShareLink{},
+	}
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		table := stmt.Schema.Table
+		if !migrator.HasTable(table) {
+			t.Errorf("no migration creates %s", table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !field.IgnoreMigration && !migrator.HasColumn(model, field.DBName) {
+				t.Errorf("no migration adds %s.%s", table, field.DBName)
+			}
+		}
+		for _, idx := range stmt.Schema.ParseIndexes() {
+			if !migrator.HasIndex(model, idx.Name) {
+				t.Errorf("no migration creates index %s on %s", idx.Name, table)
+			}
+		}
+		for _, rel := range stmt.Schema.Relationships.Relations {
+			if rel.JoinTable != nil && !migrator.HasTable(rel.JoinTable.Table) {
+				t.Errorf("no migration creates join table %s", rel.JoinTable.Table)
+			}
+		}
+	}
+}
+
+func TestMigrateDryRunLeavesDatabaseUntouched(t *testing.T) {
+	t.Parallel()
+
+	path := copyFixture(t)
+	pending, err := Migrate(path, context.Background(), nil, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(pending) != len(migrations) {
+		t.Fatalf("dry run reported %d pending migrations, want %d", len(pending), len(migrations))
+	}
+
+	gdb, err := open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if sqlDB, err := gdb.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+	if gdb.Migrator().HasTable(&schemaMigration{}) {
+		t.Fatal("dry run created schema_migrations")
+	}
+	if !gdb.Migrator().HasColumn(&types.ImageBlob{}, "data") {
+		t.Fatal("dry run moved legacy media")
+	}
+}
+
+func TestMigrateLegacyFixture(t *testing.T) {
+	t.Parallel()
+
+	path := copyFixture(t)
+	store, err := blob.NewLocal(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	applied, err := Migrate(path, ctx, store, false)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(migrations))
+	}
+
+	pending, err := Migrate(path, ctx, store, false)
+	if err != nil {
+		t.Fatalf("re-run migrate: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("second run applied %d migrations, want 0", len(pending))
+	}
+
+	conn, err := Connect(path, ctx, store)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Stop()
+	s := conn.(*sqliteDB)
+
+	var versions []int
+	if err := s.db.Model(&schemaMigration{}).Order("version").Pluck("version", &versions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != len(migrations) || versions[len(versions)-1] != migrations[len(migrations)-1].Version {
+		t.Fatalf("schema_migrations = %v", versions)
+	}
+
+	migrator := s.db.Migrator()
+	if migrator.HasColumn(&types.ImageBlob{}, "data") || migrator.HasColumn(&types.Image{}, "thumbnail") {
+		t.Fatal("legacy BLOB columns were not dropped")
+	}
+
+	for _, id := range []uint{1, 2} {
+		b, err := conn.GetImageBlob(id)
+		if err != nil {
+			t.Fatalf("GetImageBlob(%d): %v", id, err)
+		}
+		if !bytes.Equal(b.Data, []byte("payload")) {
+			t.Fatalf("blob %d data = %q", id, b.Data)
+		}
+		if b.Size != int64(len("payload")) {
+			t.Fatalf("blob %d size = %d", id, b.Size)
+		}
+		if want := []string{"image/png", "text/plain; charset=utf-8"}[id-1]; b.ContentType != want {
+			t.Fatalf("blob %d content type = %q, want %q", id, b.ContentType, want)
+		}
+	}
+
+	thumb, err := conn.GetImageThumbnailByBlobID(1)
+	if err != nil || !bytes.Equal(thumb, []byte("thumb")) {
+		t.Fatalf("thumbnail = %q, %v", thumb, err)
+	}
+
+	var obj types.BlobObject
+	if err := s.db.Where("checksum = ?", checksum([]byte("payload"))).First(&obj).Error; err != nil {
+		t.Fatalf("blob object: %v", err)
+	}
+	if obj.RefCount != 2 {
+		t.Fatalf("shared payload RefCount = %d, want 2", obj.RefCount)
+	}
+
+	var orphans int64
+	if err := s.db.Model(&types.BlobObject{}).Where("checksum = ?", checksum([]byte("orphan"))).Count(&orphans).Error; err != nil {
+		t.Fatal(err)
+	}
+	if orphans != 0 {
+		t.Fatal("payload of a deleted post was kept")
+	}
+	if _, err := store.Stat(ctx, contentKey(checksum([]byte("orphan")))); err == nil {
+		t.Fatal("payload of a deleted post is still in the store")
+	}
+}
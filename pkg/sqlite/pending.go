@@ -0,0 +1,176 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gorm.io/gorm"
+
+	"drigo/pkg/blob"
+	"drigo/pkg/types"
+)
+
+// pendingPrefix is the blob store prefix for media of pending /post commands.
+// These objects are not content addressed; each belongs to one pending post.
+const pendingPrefix = "pending"
+
+// CreatePendingPost stores p and writes its thumbnail and media to the blob
+// store. Data is left in memory for the caller; media without Data is kept
+// as a reference only.
+func (s *sqliteDB) CreatePendingPost(p *types.PendingPost) error {
+	if p == nil || p.PostKey == "" {
+		return errors.New("invalid pending post")
+	}
+	p.ExpiresAt = p.ExpiresAt.UTC()
+
+	var written []string
+	put := func(data []byte, contentType string) (string, error) {
+		info, err := s.store.Put(s.ctx, blob.NewKey(pendingPrefix), bytes.NewReader(data), contentType)
+		if err != nil {
+			return "", err
+		}
+		written = append(written, info.Key)
+		return info.Key, nil
+	}
+	fail := func(err error) error {
+		deletePendingObjects(s.ctx, s.store, written)
+		return err
+	}
+
+	if len(p.Thumbnail) > 0 {
+		key, err := put(p.Thumbnail, "image/webp")
+		if err != nil {
+			return fail(fmt.Errorf("store pending thumbnail: %w", err))
+		}
+		p.ThumbnailKey = key
+	}
+	for i := range p.Media {
+		m := &p.Media[i]
+		m.Index = i
+		if len(m.Data) == 0 {
+			continue
+		}
+		key, err := put(m.Data, m.ContentType)
+		if err != nil {
+			return fail(fmt.Errorf("store pending media %q: %w", m.Filename, err))
+		}
+		m.StorageKey = key
+		m.Size = int64(len(m.Data))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.db.Create(p).Error; err != nil {
+		return fail(err)
+	}
+	return nil
+}
+
+// PendingPost returns the pending post with key and loads its media, or nil
+// if it doesn't exist or has expired.
+func (s *sqliteDB) PendingPost(key string) (*types.PendingPost, error) {
+	var p types.PendingPost
+	err := func() error {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.db.Preload("Media", func(db *gorm.DB) *gorm.DB { return db.Order("`index`") }).
+			Where("post_key = ? AND expires_at > ?", key, time.Now().UTC()).
+			First(&p).Error
+	}()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.ThumbnailKey != "" {
+		if p.Thumbnail, err = s.store.Get(s.ctx, p.ThumbnailKey); err != nil {
+			return nil, fmt.Errorf("read pending thumbnail: %w", err)
+		}
+	}
+	for i := range p.Media {
+		m := &p.Media[i]
+		if m.StorageKey == "" {
+			continue
+		}
+		if m.Data, err = s.store.Get(s.ctx, m.StorageKey); err != nil {
+			return nil, fmt.Errorf("read pending media %q: %w", m.Filename, err)
+		}
+	}
+	return &p, nil
+}
+
+// DeletePendingPost removes a pending post and its media. Deleting a missing
+// one is not an error.
+func (s *sqliteDB) DeletePendingPost(key string) error {
+	keys, err := s.deletePendingRows([]string{key})
+	if err != nil {
+		return err
+	}
+	deletePendingObjects(s.ctx, s.store, keys)
+	return nil
+}
+
+// DeleteExpiredPendingPosts removes pending posts that expired before now
+// and returns how many there were.
+func (s *sqliteDB) DeleteExpiredPendingPosts(now time.Time) (int, error) {
+	now = now.UTC()
+	var postKeys []string
+	err := func() error {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.db.Model(&types.PendingPost{}).Where("expires_at <= ?", now).Pluck("post_key", &postKeys).Error
+	}()
+	if err != nil || len(postKeys) == 0 {
+		return 0, err
+	}
+
+	keys, err := s.deletePendingRows(postKeys)
+	if err != nil {
+		return 0, err
+	}
+	deletePendingObjects(s.ctx, s.store, keys)
+	return len(postKeys), nil
+}
+
+// deletePendingRows deletes the given pending posts and their media rows and
+// returns the storage keys they referenced.
+func (s *sqliteDB) deletePendingRows(postKeys []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var thumbs, media []string
+		if err := tx.Model(&types.PendingPost{}).
+			Where("post_key IN ? AND thumbnail_key <> ''", postKeys).
+			Pluck("thumbnail_key", &thumbs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&types.PendingMedia{}).Where("post_key IN ? AND storage_key <> ''", postKeys).Pluck("storage_key", &media).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("post_key IN ?", postKeys).Delete(&types.PendingMedia{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("post_key IN ?", postKeys).Delete(&types.PendingPost{}).Error; err != nil {
+			return err
+		}
+		keys = append(thumbs, media...)
+		return nil
+	})
+	return keys, err
+}
+
+// deletePendingObjects removes pending media from the store.
+func deletePendingObjects(ctx context.Context, store blob.Store, keys []string) {
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			log.Warn("Failed to delete pending media", "key", key, "error", err)
+		}
+	}
+}
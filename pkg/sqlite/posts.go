@@ -12,14 +12,31 @@ import (
 
 // CreatePost inserts a new post with its associations (Author, Image, AllowedRoles).
 // It expects p.Author, p.Image, and p.AllowedRoles to be already set as desired.
+// The post is published now unless it is a draft or p.PublishAt is in the future.
 func (s *sqliteDB) CreatePost(p *types.Post) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
 	if p == nil {
 		return errors.New("nil post")
 	}
+	s.blobMu.Lock()
+	defer s.blobMu.Unlock()
+	// Write payloads before taking the lock so large uploads don't stall readers.
+	touched, err := s.putImages(p.Images)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.release(touched)
+	now := time.Now().UTC()
 	if p.Timestamp.IsZero() {
-		p.Timestamp = time.Now().UTC()
+		p.Timestamp = now
+	}
+	// Posts go live immediately unless drafted or scheduled for later.
+	if p.Draft {
+		p.PublishAt, p.PublishedAt = nil, nil
+	} else if p.PublishedAt == nil && (p.PublishAt == nil || !p.PublishAt.After(now)) {
+		p.PublishedAt = &now
 	}
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		if p.Author != nil {
@@ -60,7 +77,7 @@ func (s *sqliteDB) CreatePost(p *types.Post) error {
 			p.Author = &dbUser
 		}
 
-		if err := tx.Omit("Author", "Images", "AllowedRoles").Create(p).Error; err != nil {
+		if err := tx.Omit("Author", "Images", "AllowedRoles", "Tags").Create(p).Error; err != nil {
 			return err
 		}
 
@@ -91,26 +108,9 @@ func (s *sqliteDB) CreatePost(p *types.Post) error {
 
 		// Allowed roles (optional). Ensure each role row exists; then attach.
 		if len(p.AllowedRoles) > 0 {
-			resolvedRoles := make([]types.Allowed, 0, len(p.AllowedRoles))
-			for i := range p.AllowedRoles {
-				ar := &p.AllowedRoles[i]
-				if ar.RoleID == "" {
-					continue
-				}
-				if err := tx.Clauses(
-					clause.OnConflict{
-						Columns:   []clause.Column{{Name: "role_id"}},
-						DoUpdates: clause.AssignmentColumns([]string{"name", "managed", "mentionable", "hoist", "color", "position", "permissions", "icon", "unicode_emoji", "flags"}),
-					},
-				).Create(ar).Error; err != nil {
-					return err
-				}
-
-				var resolved types.Allowed
-				if err := tx.Where("role_id = ?", ar.RoleID).First(&resolved).Error; err != nil {
-					return err
-				}
-				resolvedRoles = append(resolvedRoles, resolved)
+			resolvedRoles, err := resolveAllowedRoles(tx, p.AllowedRoles)
+			if err != nil {
+				return err
 			}
 			p.AllowedRoles = resolvedRoles
 			if err := tx.Model(p).Association("AllowedRoles").Replace(p.AllowedRoles); err != nil {
@@ -118,7 +118,13 @@ func (s *sqliteDB) CreatePost(p *types.Post) error {
 			}
 		}
 
-		return nil
+		if len(p.Tags) > 0 {
+			if err := replacePostTags(tx, p); err != nil {
+				return err
+			}
+		}
+
+		return reindexPost(tx, p.ID)
 	})
 }
 
@@ -130,12 +136,13 @@ func (s *sqliteDB) ReadPost(id uint) (*types.Post, error) {
 	err := s.db.
 		Preload("Author").
 		Preload("Images", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id", "created_at", "updated_at", "deleted_at", "post_id", "(CASE WHEN length(thumbnail) > 0 THEN 1 ELSE 0 END) as has_thumbnail")
+			return db.Select("id", "created_at", "updated_at", "deleted_at", "post_id", "thumbnail_key", "thumbnail_checksum", "thumbnail_blurred", "(CASE WHEN thumbnail_key <> '' THEN 1 ELSE 0 END) as has_thumbnail")
 		}).
 		Preload("Images.Blobs", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id", "created_at", "updated_at", "deleted_at", "image_id", "index", "content_type", "filename", "length(data) as size")
+			return db.Select("id", "created_at", "updated_at", "deleted_at", "image_id", "index", "storage_key", "checksum", "content_type", "filename", "size")
 		}).
 		Preload("AllowedRoles").
+		Preload("Tags").
 		First(&p, id).Error
 	if err != nil {
 		return nil, err
@@ -147,7 +154,6 @@ func (s *sqliteDB) ReadPost(id uint) (*types.Post, error) {
 // Use this for edit flows that must preserve existing binary data.
 func (s *sqliteDB) ReadPostWithBlobData(id uint) (*types.Post, error) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
 	var p types.Post
 	err := s.db.
 		Preload("Author").
@@ -156,10 +162,15 @@ func (s *sqliteDB) ReadPostWithBlobData(id uint) (*types.Post, error) {
 			return db.Order("`image_blobs`.`index` ASC")
 		}).
 		Preload("AllowedRoles").
+		Preload("Tags").
 		First(&p, id).Error
+	s.mu.RUnlock()
 	if err != nil {
 		return nil, err
 	}
+	if err := s.loadImages(p.Images); err != nil {
+		return nil, err
+	}
 	return &p, nil
 }
 
@@ -171,12 +182,13 @@ func (s *sqliteDB) ReadPostByExternalID(ext string) (*types.Post, error) {
 	err := s.db.
 		Preload("Author").
 		Preload("Images", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id", "created_at", "updated_at", "deleted_at", "post_id", "(CASE WHEN length(thumbnail) > 0 THEN 1 ELSE 0 END) as has_thumbnail")
+			return db.Select("id", "created_at", "updated_at", "deleted_at", "post_id", "thumbnail_key", "thumbnail_checksum", "thumbnail_blurred", "(CASE WHEN thumbnail_key <> '' THEN 1 ELSE 0 END) as has_thumbnail")
 		}).
 		Preload("Images.Blobs", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id", "created_at", "updated_at", "deleted_at", "image_id", "index", "content_type", "filename", "length(data) as size")
+			return db.Select("id", "created_at", "updated_at", "deleted_at", "image_id", "index", "storage_key", "checksum", "content_type", "filename", "size")
 		}).
 		Preload("AllowedRoles").
+		Preload("Tags").
 		Where("post_key = ?", ext).
 		First(&p).Error
 	if err != nil {
@@ -189,7 +201,6 @@ func (s *sqliteDB) ReadPostByExternalID(ext string) (*types.Post, error) {
 // Use this for edit flows that must preserve existing binary data.
 func (s *sqliteDB) ReadPostByExternalIDWithBlobData(ext string) (*types.Post, error) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
 	var p types.Post
 	err := s.db.
 		Preload("Author").
@@ -198,23 +209,35 @@ func (s *sqliteDB) ReadPostByExternalIDWithBlobData(ext string) (*types.Post, er
 			return db.Order("`image_blobs`.`index` ASC")
 		}).
 		Preload("AllowedRoles").
+		Preload("Tags").
 		Where("post_key = ?", ext).
 		First(&p).Error
+	s.mu.RUnlock()
 	if err != nil {
 		return nil, err
 	}
+	if err := s.loadImages(p.Images); err != nil {
+		return nil, err
+	}
 	return &p, nil
 }
 
 // UpdatePost replaces the post row and its associations to match p exactly.
-// Use this when you want to overwrite AllowedRoles and Image in one go.
+// Use this when you want to overwrite AllowedRoles, Tags and Image in one go.
 func (s *sqliteDB) UpdatePost(p *types.Post) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
 	if p == nil || p.ID == 0 {
 		return errors.New("invalid post (nil or no ID)")
 	}
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	s.blobMu.Lock()
+	defer s.blobMu.Unlock()
+	touched, err := s.putImages(p.Images)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
 		if p.Author != nil {
 			var dbUser types.User
 			q := tx.Where("user_id = ?", p.Author.UserID).First(&dbUser)
@@ -251,23 +274,19 @@ func (s *sqliteDB) UpdatePost(p *types.Post) error {
 			p.Author = &dbUser
 		}
 
+		old, err := postChecksums(tx, p.ID)
+		if err != nil {
+			return err
+		}
+		touched = append(touched, old...)
+
 		// Images (optional) — ensure the image rows belong to this post and replace blobs
 		if len(p.Images) > 0 {
-			// First, remove all existing images for this post to handle replacements cleanly
+			// First, retire all existing images for this post to handle replacements cleanly
 			// (Optimization: could diff IDs but full replacement is safer and simpler for execution)
-			var existingImgs []types.Image
-			if err := tx.Where("post_id = ?", p.ID).Find(&existingImgs).Error; err != nil {
+			if err := retireImages(tx, p.ID, p.Images); err != nil {
 				return err
 			}
-			for i := range existingImgs {
-				img := existingImgs[i]
-				if err := tx.Unscoped().Where("image_id = ?", img.ID).Delete(&types.ImageBlob{}).Error; err != nil {
-					return err
-				}
-				if err := tx.Unscoped().Delete(&types.Image{}, img.ID).Error; err != nil {
-					return err
-				}
-			}
 
 			// Add new images
 			for i := range p.Images {
@@ -291,24 +310,14 @@ func (s *sqliteDB) UpdatePost(p *types.Post) error {
 				img.Blobs = blobs
 			}
 		} else {
-			// Remove any existing image for this post
-			var imgs []types.Image
-			if err := tx.Where("post_id = ?", p.ID).Find(&imgs).Error; err != nil {
+			// Retire any existing image for this post
+			if err := retireImages(tx, p.ID, nil); err != nil {
 				return err
 			}
-			for i := range imgs {
-				img := imgs[i]
-				if err := tx.Unscoped().Where("image_id = ?", img.ID).Delete(&types.ImageBlob{}).Error; err != nil {
-					return err
-				}
-				if err := tx.Unscoped().Delete(&types.Image{}, img.ID).Error; err != nil {
-					return err
-				}
-			}
 		}
 
 		// Update post core fields (omit associations, handled above)
-		if err := tx.Omit("Author", "Images", "AllowedRoles").Save(p).Error; err != nil {
+		if err := tx.Omit("Author", "Images", "AllowedRoles", "Tags").Save(p).Error; err != nil {
 			return err
 		}
 
@@ -318,26 +327,9 @@ func (s *sqliteDB) UpdatePost(p *types.Post) error {
 			return err
 		}
 		if len(rolesToApply) > 0 {
-			resolvedRoles := make([]types.Allowed, 0, len(rolesToApply))
-			for i := range rolesToApply {
-				ar := &rolesToApply[i]
-				if ar.RoleID == "" {
-					continue
-				}
-				if err := tx.Clauses(
-					clause.OnConflict{
-						Columns:   []clause.Column{{Name: "role_id"}},
-						DoUpdates: clause.AssignmentColumns([]string{"name", "managed", "mentionable", "hoist", "color", "position", "permissions", "icon", "unicode_emoji", "flags"}),
-					},
-				).Create(ar).Error; err != nil {
-					return err
-				}
-
-				var resolved types.Allowed
-				if err := tx.Where("role_id = ?", ar.RoleID).First(&resolved).Error; err != nil {
-					return err
-				}
-				resolvedRoles = append(resolvedRoles, resolved)
+			resolvedRoles, err := resolveAllowedRoles(tx, rolesToApply)
+			if err != nil {
+				return err
 			}
 			if err := tx.Model(p).Association("AllowedRoles").Replace(resolvedRoles); err != nil {
 				return err
@@ -347,17 +339,26 @@ func (s *sqliteDB) UpdatePost(p *types.Post) error {
 			p.AllowedRoles = nil
 		}
 
-		return nil
+		// Tags (replace association)
+		if err := replacePostTags(tx, p); err != nil {
+			return err
+		}
+
+		return reindexPost(tx, p.ID)
 	})
+	s.release(touched)
+	return err
 }
 
 // PostPatch updates selected scalar fields without touching associations unless specified.
 type PostPatch struct {
-	Content   *string
-	Timestamp *time.Time
-	IsPremium *bool
-	ChannelID *string
-	GuildID   *string
+	Title       *string
+	Description *string
+	Content     *string
+	Timestamp   *time.Time
+	IsPremium   *bool
+	ChannelID   *string
+	GuildID     *string
 
 	// Optional association ops
 	ReplaceAllowedRoles []*types.Allowed // if set, replaces AllowedRoles with these
@@ -369,12 +370,19 @@ type PostPatch struct {
 
 // PatchPost applies partial updates to a post by PK.
 func (s *sqliteDB) PatchPost(id uint, patch PostPatch) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
 	if id == 0 {
 		return errors.New("invalid id")
 	}
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	s.blobMu.Lock()
+	defer s.blobMu.Unlock()
+	touched, err := s.putImages(patch.ReplaceImages)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
 		var p types.Post
 		if err := tx.First(&p, id).Error; err != nil {
 			return err
@@ -382,6 +390,12 @@ func (s *sqliteDB) PatchPost(id uint, patch PostPatch) error {
 
 		// Scalars
 		updates := map[string]any{}
+		if patch.Title != nil {
+			updates["title"] = *patch.Title
+		}
+		if patch.Description != nil {
+			updates["description"] = *patch.Description
+		}
 		if patch.Content != nil {
 			updates["content"] = *patch.Content
 		}
@@ -431,37 +445,24 @@ func (s *sqliteDB) PatchPost(id uint, patch PostPatch) error {
 		}
 
 		// Images
-		if patch.ClearImages {
-			// delete image and child blobs if present for this post
-			var imgs []types.Image
-			if err := tx.Where("post_id = ?", p.ID).Find(&imgs).Error; err != nil {
+		if patch.ClearImages || patch.ReplaceImages != nil {
+			old, err := postChecksums(tx, p.ID)
+			if err != nil {
 				return err
 			}
-			for i := range imgs {
-				img := imgs[i]
-				if err := tx.Unscoped().Where("image_id = ?", img.ID).Delete(&types.ImageBlob{}).Error; err != nil {
-					return err
-				}
-				if err := tx.Unscoped().Delete(&types.Image{}, img.ID).Error; err != nil {
-					return err
-				}
+			touched = append(touched, old...)
+		}
+		if patch.ClearImages {
+			// retire image and child blobs if present for this post
+			if err := retireImages(tx, p.ID, nil); err != nil {
+				return err
 			}
 		} else if patch.ReplaceImages != nil {
 			// replace current images for this post with the provided ones
-			// first remove any existing images for this post
-			var existingImgs []types.Image
-			if err := tx.Where("post_id = ?", p.ID).Find(&existingImgs).Error; err != nil {
+			// first retire any existing images for this post
+			if err := retireImages(tx, p.ID, patch.ReplaceImages); err != nil {
 				return err
 			}
-			for i := range existingImgs {
-				img := existingImgs[i]
-				if err := tx.Unscoped().Where("image_id = ?", img.ID).Delete(&types.ImageBlob{}).Error; err != nil {
-					return err
-				}
-				if err := tx.Unscoped().Delete(&types.Image{}, img.ID).Error; err != nil {
-					return err
-				}
-			}
 			for _, img := range patch.ReplaceImages {
 				img.ID = 0
 				img.PostID = p.ID
@@ -483,51 +484,88 @@ func (s *sqliteDB) PatchPost(id uint, patch PostPatch) error {
 			}
 		}
 
-		return nil
+		return reindexPost(tx, p.ID)
 	})
+	s.release(touched)
+	return err
 }
 
-// DeletePost removes the post and detaches associations.
-// Image is deleted (with blobs) if referenced by this post.
+// DeletePost moves the post to the trash together with its images and blobs,
+// stamping them all with the same deletion time so RestoreTrashedPost can bring
+// them back as a unit. Associations are kept; stored payloads stay in the blob
+// store until the post is purged.
 func (s *sqliteDB) DeletePost(id uint) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
 	if id == 0 {
 		return errors.New("invalid id")
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		var p types.Post
-		if err := tx.Preload("AllowedRoles").First(&p, id).Error; err != nil {
+		if err := tx.Select("id").First(&p, id).Error; err != nil {
 			return err
 		}
 
-		// Clear m2m to drop join rows
-		if err := tx.Model(&p).Association("AllowedRoles").Clear(); err != nil {
+		imageIDs := tx.Model(&types.Image{}).Select("id").Where("post_id = ?", id)
+		if err := tx.Model(&types.ImageBlob{}).Where("image_id IN (?)", imageIDs).
+			UpdateColumn("deleted_at", now).Error; err != nil {
 			return err
 		}
-
-		// Delete image + blobs if present
-		var imgs []types.Image
-		if err := tx.Where("post_id = ?", p.ID).Find(&imgs).Error; err != nil {
+		if err := tx.Model(&types.Image{}).Where("post_id = ?", id).
+			UpdateColumn("deleted_at", now).Error; err != nil {
 			return err
 		}
-		for i := range imgs {
-			img := imgs[i]
-			if err := tx.Where("image_id = ?", img.ID).Delete(&types.ImageBlob{}).Error; err != nil {
-				return err
-			}
-			if err := tx.Delete(&types.Image{}, img.ID).Error; err != nil {
-				return err
-			}
+		if err := tx.Model(&types.Post{}).Where("id = ?", id).
+			UpdateColumn("deleted_at", now).Error; err != nil {
+			return err
 		}
-
-		// Finally delete post
-		return tx.Delete(&types.Post{}, id).Error
+		return reindexPost(tx, id)
 	})
 }
 
+// resolveAllowedRoles upserts each role row and returns the stored rows,
+// skipping entries without a RoleID.
+func resolveAllowedRoles(tx *gorm.DB, roles []types.Allowed) ([]types.Allowed, error) {
+	resolvedRoles := make([]types.Allowed, 0, len(roles))
+	for i := range roles {
+		ar := &roles[i]
+		if ar.RoleID == "" {
+			continue
+		}
+		if err := tx.Clauses(
+			clause.OnConflict{
+				Columns:   []clause.Column{{Name: "role_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"name", "managed", "mentionable", "hoist", "color", "position", "permissions", "icon", "unicode_emoji", "flags"}),
+			},
+		).Create(ar).Error; err != nil {
+			return nil, err
+		}
+
+		var resolved types.Allowed
+		if err := tx.Where("role_id = ?", ar.RoleID).First(&resolved).Error; err != nil {
+			return nil, err
+		}
+		resolvedRoles = append(resolvedRoles, resolved)
+	}
+	return resolvedRoles, nil
+}
+
 // GetImageBlob fetches a specific image blob by its ID including the data.
 func (s *sqliteDB) GetImageBlob(id uint) (*types.ImageBlob, error) {
+	blob, err := s.GetImageBlobInfo(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadBlob(blob); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// GetImageBlobInfo fetches a specific image blob by its ID without reading its data.
+// Use the StorageKey with the blob store to stream the payload.
+func (s *sqliteDB) GetImageBlobInfo(id uint) (*types.ImageBlob, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var blob types.ImageBlob
@@ -541,20 +579,66 @@ func (s *sqliteDB) GetImageBlob(id uint) (*types.ImageBlob, error) {
 // GetImageThumbnailByBlobID fetches the thumbnail for the image associated with the given blob ID.
 func (s *sqliteDB) GetImageThumbnailByBlobID(blobID uint) ([]byte, error) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
 	var result struct {
-		Thumbnail []byte
+		ThumbnailKey string
 	}
 	err := s.db.Table("images").
-		Select("images.thumbnail").
+		Select("images.thumbnail_key").
 		Joins("JOIN image_blobs ON image_blobs.image_id = images.id").
 		Where("image_blobs.id = ?", blobID).
 		Scan(&result).Error
+	s.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if result.ThumbnailKey == "" {
+		return nil, nil
+	}
+	return s.store.Get(s.ctx, result.ThumbnailKey)
+}
 
+// SetImageThumbnail replaces the thumbnail of one image in place. Unlike
+// UpdatePost it keeps the image and blob rows, so their IDs stay valid.
+func (s *sqliteDB) SetImageThumbnail(imageID uint, thumb []byte, blurred bool) error {
+	s.blobMu.Lock()
+	defer s.blobMu.Unlock()
+	imgs := []types.Image{{Thumbnail: thumb}}
+	touched, err := s.putImages(imgs)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var old types.Image
+	if err := s.db.Select("id", "thumbnail_checksum").First(&old, imageID).Error; err != nil {
+		s.release(touched)
+		return err
+	}
+	err = s.db.Model(&types.Image{}).Where("id = ?", imageID).UpdateColumns(map[string]any{
+		"thumbnail_key":      imgs[0].ThumbnailKey,
+		"thumbnail_checksum": imgs[0].ThumbnailChecksum,
+		"thumbnail_blurred":  blurred,
+	}).Error
+	s.release(append(touched, old.ThumbnailChecksum))
+	return err
+}
+
+// GetImageByBlobID fetches the image row (without thumbnail data) that owns the given blob ID.
+func (s *sqliteDB) GetImageByBlobID(blobID uint) (*types.Image, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var img types.Image
+	err := s.db.
+		Select("images.id", "images.created_at", "images.updated_at", "images.post_id", "images.thumbnail_key", "images.thumbnail_checksum").
+		Joins("JOIN image_blobs ON image_blobs.image_id = images.id").
+		Where("image_blobs.id = ?", blobID).
+		First(&img).Error
 	if err != nil {
 		return nil, err
 	}
-	return result.Thumbnail, nil
+	return &img, nil
 }
 
 // GetPostByBlobID finds the post associated with a specific image blob ID.
@@ -0,0 +1,179 @@
+package sqlite
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"drigo/pkg/types"
+)
+
+// ErrAlreadyPublished is returned when scheduling a post that is already live.
+var ErrAlreadyPublished = errors.New("post is already published")
+
+// UnpublishedPosts returns scheduled posts and drafts, soonest first, with
+// drafts last.
+func (s *sqliteDB) UnpublishedPosts() ([]*types.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var posts []*types.Post
+	err := listPreloads(s.db).
+		Where("published_at IS NULL").
+		Order("publish_at IS NULL, publish_at, id").
+		Find(&posts).Error
+	return posts, err
+}
+
+// NextPublishAt returns the earliest pending PublishAt, or nil if nothing is scheduled.
+func (s *sqliteDB) NextPublishAt() (*time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var p types.Post
+	err := s.db.Select("publish_at").
+		Where("published_at IS NULL AND publish_at IS NOT NULL AND draft = ?", false).
+		Order("publish_at").
+		First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.PublishAt, nil
+}
+
+// SchedulePost sets when an unpublished post goes live, taking it out of
+// drafts. A nil at cancels the schedule and returns the post to drafts.
+func (s *sqliteDB) SchedulePost(id uint, at *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var p types.Post
+		if err := tx.Select("id", "published_at").First(&p, id).Error; err != nil {
+			return err
+		}
+		if p.PublishedAt != nil {
+			return ErrAlreadyPublished
+		}
+		if at != nil {
+			utc := at.UTC()
+			at = &utc
+		}
+		return tx.Model(&p).Updates(map[string]any{"publish_at": at, "draft": at == nil}).Error
+	})
+}
+
+// PublishDuePosts marks every post whose PublishAt has passed as published at
+// now and returns them. Each post is returned by exactly one call, so callers
+// can announce them without double posting.
+func (s *sqliteDB) PublishDuePosts(now time.Time) ([]*types.Post, error) {
+	now = now.UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&types.Post{}).
+			Where("published_at IS NULL AND publish_at <= ? AND draft = ?", now, false).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Model(&types.Post{}).Where("id IN ?", ids).Update("published_at", now).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := reindexPost(tx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var posts []*types.Post
+	if err := listPreloads(s.db).Where("id IN ?", ids).Order("publish_at, id").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// PublishPost publishes a draft or scheduled post immediately.
+func (s *sqliteDB) PublishPost(id uint, now time.Time) (*types.Post, error) {
+	now = now.UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var p types.Post
+		if err := tx.Select("id", "published_at").First(&p, id).Error; err != nil {
+			return err
+		}
+		if p.PublishedAt != nil {
+			return ErrAlreadyPublished
+		}
+		if err := tx.Model(&p).Updates(map[string]any{"published_at": now, "publish_at": nil, "draft": false}).Error; err != nil {
+			return err
+		}
+		return reindexPost(tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var p types.Post
+	if err := listPreloads(s.db).First(&p, id).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// NextPublicAfter returns the earliest early-access window still to open on a
+// published post, or nil if there is none.
+func (s *sqliteDB) NextPublicAfter() (*time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var posts []types.Post
+	err := s.db.Select("public_after").
+		Where("published_at IS NOT NULL AND public_after IS NOT NULL AND went_public_at IS NULL").
+		Order("public_after").
+		Limit(1).
+		Find(&posts).Error
+	if err != nil || len(posts) == 0 {
+		return nil, err
+	}
+	return posts[0].PublicAfter, nil
+}
+
+// OpenDuePosts marks every published post whose early-access window has closed
+// as gone public at now and returns them. Like PublishDuePosts, each post is
+// returned by exactly one call.
+func (s *sqliteDB) OpenDuePosts(now time.Time) ([]*types.Post, error) {
+	now = now.UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&types.Post{}).
+			Where("published_at IS NOT NULL AND public_after <= ? AND went_public_at IS NULL", now).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&types.Post{}).Where("id IN ?", ids).Update("went_public_at", now).Error
+	})
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var posts []*types.Post
+	if err := listPreloads(s.db).Where("id IN ?", ids).Order("public_after, id").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
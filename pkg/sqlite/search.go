@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+
+	"drigo/pkg/types"
+)
+
+// posts_fts is an FTS5 index over the searchable text of live posts, keyed by
+// posts.id. It is rebuilt per post inside the transactions that change them.
+const createPostsFTS = `CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(
+	title, description, tags, filenames,
+	tokenize = 'unicode61 remove_diacritics 2'
+)`
+
+// postDocumentSQL selects the indexed columns of live, published, unarchived
+// posts.
+const postDocumentSQL = `SELECT p.id, p.title, p.description,
+	COALESCE((
+		SELECT group_concat(t.name, ' ') FROM post_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.post_id = p.id
+	), ''),
+	COALESCE((
+		SELECT group_concat(b.filename, ' ') FROM image_blobs b
+		JOIN images i ON i.id = b.image_id
+		WHERE i.post_id = p.id AND i.deleted_at IS NULL AND b.deleted_at IS NULL
+	), '')
+FROM posts p WHERE p.deleted_at IS NULL AND p.published_at IS NOT NULL AND p.archived_at IS NULL`
+
+// lockedColumns are the columns a post's preview already shows, and so the
+// only ones searched for posts the caller can't open.
+const lockedColumns = "{title description tags}"
+
+// bm25 weights for title, description, tags and filenames.
+const searchRank = "bm25(posts_fts, 10.0, 4.0, 6.0, 1.0)"
+
+// Snippet highlight markers (char(2) and char(3) in SQL), swapped for <mark>
+// tags after HTML escaping.
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// SearchOptions controls SearchPosts.
+type SearchOptions struct {
+	Query  string
+	Limit  int
+	Offset int
+
+	// AllAccess lets every post match on every column (admins, public galleries).
+	// Otherwise posts UserID holding RoleIDs may not view only match on the
+	// text their preview already shows.
+	AllAccess bool
+	UserID    string
+	RoleIDs   []string
+}
+
+// reindexPost refreshes the search document for one post, dropping it if the
+// post has been deleted or is not published.
+func reindexPost(tx *gorm.DB, id uint) error {
+	if err := tx.Exec("DELETE FROM posts_fts WHERE rowid = ?", id).Error; err != nil {
+		return err
+	}
+	return tx.Exec("INSERT INTO posts_fts(rowid, title, description, tags, filenames) "+postDocumentSQL+" AND p.id = ?", id).Error
+}
+
+// rebuildPostsFTS reindexes every live post.
+func rebuildPostsFTS(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM posts_fts").Error; err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO posts_fts(rowid, title, description, tags, filenames) " + postDocumentSQL).Error
+	})
+}
+
+// ftsQuery turns free text into an FTS5 expression matching every term as a
+// prefix, so user input can never be a syntax error.
+func ftsQuery(q string) string {
+	var terms []string
+	for field := range strings.FieldsSeq(q) {
+		if !strings.ContainsFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) {
+			continue
+		}
+		terms = append(terms, `"`+strings.ReplaceAll(field, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// highlight escapes a snippet for HTML and wraps matches in <mark> tags.
+func highlight(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, markStart, "<mark>")
+	return strings.ReplaceAll(snippet, markEnd, "</mark>")
+}
+
+// SearchPosts returns posts matching opts.Query, best match first, each with a
+// highlighted Snippet.
+func (s *sqliteDB) SearchPosts(opts SearchOptions) ([]*types.Post, error) {
+	match := ftsQuery(opts.Query)
+	if match == "" {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Posts with no live restriction, or one the caller's grants, roles or tier meet.
+	accessible := s.db.Table("posts AS p").Select("p.id").Where("p.deleted_at IS NULL")
+	if !opts.AllAccess {
+		query, args := s.viewableClause("p", opts.UserID, opts.RoleIDs)
+		accessible = accessible.Where(query, args...)
+	}
+
+	const snippet = "snippet(posts_fts, -1, char(2), char(3), '…', 12)"
+	var hits []struct {
+		ID      uint
+		Snippet string
+	}
+	err := s.db.Raw(
+		"SELECT id, snippet FROM ("+
+			"SELECT rowid AS id, "+searchRank+" AS rank, "+snippet+" AS snippet FROM posts_fts WHERE posts_fts MATCH ? AND rowid IN (?) "+
+			"UNION ALL "+
+			"SELECT rowid AS id, "+searchRank+" AS rank, "+snippet+" AS snippet FROM posts_fts WHERE posts_fts MATCH ? AND rowid NOT IN (?)"+
+			") ORDER BY rank, id DESC LIMIT ? OFFSET ?",
+		match, accessible, lockedColumns+" : ("+match+")", accessible, opts.Limit, opts.Offset,
+	).Scan(&hits).Error
+	if err != nil || len(hits) == 0 {
+		return nil, err
+	}
+
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	var found []*types.Post
+	if err := listPreloads(s.db).Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*types.Post, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+
+	posts := make([]*types.Post, 0, len(hits))
+	for _, hit := range hits {
+		if p, ok := byID[hit.ID]; ok {
+			p.Snippet = highlight(hit.Snippet)
+			posts = append(posts, p)
+		}
+	}
+	return posts, nil
+}
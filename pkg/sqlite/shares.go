@@ -0,0 +1,124 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"drigo/pkg/types"
+)
+
+// CreateShareLink stores a new share link.
+func (s *sqliteDB) CreateShareLink(l *types.ShareLink) error {
+	if l == nil || l.PostID == 0 || l.Token == "" {
+		return errors.New("invalid share link")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Create(l).Error
+}
+
+// ShareLinkByToken returns the link with token, or nil if there is none.
+// Expired and revoked links are returned too; callers check Live.
+func (s *sqliteDB) ShareLinkByToken(token string) (*types.ShareLink, error) {
+	if token == "" {
+		return nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var links []types.ShareLink
+	if err := s.db.Where("token = ?", token).Limit(1).Find(&links).Error; err != nil || len(links) == 0 {
+		return nil, err
+	}
+	return &links[0], nil
+}
+
+// PostShareLinks returns every share link of a post, newest first.
+func (s *sqliteDB) PostShareLinks(postID uint) ([]types.ShareLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var links []types.ShareLink
+	err := s.db.Where("post_id = ?", postID).Order("id DESC").Find(&links).Error
+	return links, err
+}
+
+// UseShareLink counts one use of a link, reporting false if the link is no
+// longer live or has no uses left. The check and the increment are a single
+// statement so concurrent opens can't overshoot MaxUses.
+func (s *sqliteDB) UseShareLink(id uint, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.db.Model(&types.ShareLink{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", id, now.UTC()).
+		Where("max_uses = 0 OR uses < max_uses").
+		UpdateColumn("uses", gorm.Expr("uses + 1"))
+	return res.RowsAffected == 1, res.Error
+}
+
+// RevokeShareLink ends a link immediately. Revoking twice keeps the first time.
+func (s *sqliteDB) RevokeShareLink(id uint, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.db.Model(&types.ShareLink{}).Where("id = ?", id).
+		UpdateColumn("revoked_at", gorm.Expr("COALESCE(revoked_at, ?)", now.UTC()))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// migrateShareMedia resolves the media indices share links used to store into
+// checksums, using the posts' current media, and drops the old column.
+func migrateShareMedia(db *gorm.DB) error {
+	if !db.Migrator().HasColumn("share_links", "media") {
+		return nil
+	}
+	var rows []struct {
+		ID     uint
+		PostID uint
+		Media  string
+	}
+	if err := db.Table("share_links").Select("id", "post_id", "media").
+		Where("media IS NOT NULL AND media <> '' AND media <> 'null' AND media <> '[]'").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		var indices []int
+		if err := json.Unmarshal([]byte(row.Media), &indices); err != nil || len(indices) == 0 {
+			continue
+		}
+		var images []types.Image
+		if err := db.Preload("Blobs").Where("post_id = ?", row.PostID).Order("id").Find(&images).Error; err != nil {
+			return err
+		}
+		var sums []string
+		for _, n := range indices {
+			if n >= 0 && n < len(images) {
+				for _, b := range images[n].Blobs {
+					sums = append(sums, b.Checksum)
+				}
+			}
+		}
+		slices.Sort(sums)
+		// A link whose media are all gone keeps a placeholder rather than
+		// falling back to sharing everything.
+		if len(sums) == 0 {
+			sums = []string{""}
+		}
+		data, err := json.Marshal(slices.Compact(sums))
+		if err != nil {
+			return err
+		}
+		if err := db.Table("share_links").Where("id = ?", row.ID).Update("media_checksums", string(data)).Error; err != nil {
+			return err
+		}
+	}
+	return db.Exec("ALTER TABLE share_links DROP COLUMN media").Error
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"sync"
+	"time"
 
 	gormsqlite "gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 
+	"drigo/pkg/blob"
 	"drigo/pkg/types"
 )
 
@@ -30,13 +32,18 @@ type DB interface {
 	UpdatePost(p *types.Post) error
 	DeletePost(id uint) error
 	PatchPost(id uint, patch PostPatch) error
-	ListPosts(limit, offset int, sort string) ([]*types.Post, error)
+	ListPosts(opts ListOptions) ([]*types.Post, error)
+	SearchPosts(opts SearchOptions) ([]*types.Post, error)
+	PostTitles(query string, limit int) ([]types.PostRef, error)
 	UserByID(id string) (*types.User, error)
 	UpsertUser(user *types.User) error
 	CountUsers() (int64, error)
 	SetAdmin(id string, isAdmin bool) error
 	GetImageBlob(id uint) (*types.ImageBlob, error)
+	GetImageBlobInfo(id uint) (*types.ImageBlob, error)
 	GetImageThumbnailByBlobID(blobID uint) ([]byte, error)
+	SetImageThumbnail(imageID uint, thumb []byte, blurred bool) error
+	GetImageByBlobID(blobID uint) (*types.Image, error)
 	GetPostByBlobID(blobID uint) (*types.Post, error)
 	GetSettings() (*types.Settings, error)
 	UpdateSettings(settings types.Settings) (*types.Settings, error)
@@ -46,18 +53,118 @@ type DB interface {
 	UpdateCachedChannels(channels []types.CachedChannel) error
 	GetCachedRoles() ([]types.CachedRole, error)
 	GetCachedChannels() ([]types.CachedChannel, error)
+	// Resumable uploads
+	CreateUpload(u *types.Upload) error
+	GetUpload(id string) (*types.Upload, error)
+	SaveUploadProgress(u *types.Upload) error
+	DeleteUpload(id string) error
+	ExpiredUploads(now time.Time) ([]types.Upload, error)
+	// Tags
+	ListTags() ([]types.Tag, error)
+	CreateTag(name string) (*types.Tag, error)
+	RenameTag(id uint, name string) (*types.Tag, error)
+	DeleteTag(id uint) error
+	// Collections
+	ListCollections() ([]*types.Collection, error)
+	ReadCollection(id uint) (*types.Collection, error)
+	CreateCollection(c *types.Collection, postIDs []uint) error
+	UpdateCollection(c *types.Collection) error
+	SetCollectionPosts(id uint, postIDs []uint) error
+	DeleteCollection(id uint) error
+	PostCollections(postID uint) ([]types.CollectionNav, error)
+	// Drafts and scheduled publishing
+	UnpublishedPosts() ([]*types.Post, error)
+	NextPublishAt() (*time.Time, error)
+	SchedulePost(id uint, at *time.Time) error
+	PublishDuePosts(now time.Time) ([]*types.Post, error)
+	NextPublicAfter() (*time.Time, error)
+	OpenDuePosts(now time.Time) ([]*types.Post, error)
+	PublishPost(id uint, now time.Time) (*types.Post, error)
+	// Expiry and archive
+	NextExpiresAt() (*time.Time, error)
+	ArchiveDuePosts(now time.Time) ([]*types.Post, error)
+	ArchivedPosts(limit, offset int) ([]*types.Post, error)
+	RestorePost(id uint, expiresAt *time.Time) (*types.Post, error)
+	// Trash
+	TrashedPosts(limit, offset int) ([]*types.Post, error)
+	TrashedMedia(limit, offset int) ([]types.Image, error)
+	RestoreTrashedPost(id uint) (*types.Post, error)
+	RestoreTrashedMedia(id uint) (*types.Post, error)
+	PurgePost(id uint) error
+	PurgeMedia(id uint) error
+	PurgeTrash(before time.Time) (int, error)
+	// Discord announcements
+	AddPostMessage(m *types.PostMessage) error
+	PostMessages(postID uint) ([]types.PostMessage, error)
+	PostKeyByMessageID(messageID string) (string, error)
+	DeletePostMessage(messageID string) error
+	// Pending /post commands
+	CreatePendingPost(p *types.PendingPost) error
+	PendingPost(key string) (*types.PendingPost, error)
+	DeletePendingPost(key string) error
+	DeleteExpiredPendingPosts(now time.Time) (int, error)
+	// Member access
+	RecordDownload(userID string, postID uint) error
+	DownloadedPosts(userID string) ([]*types.Post, error)
+	MarkRolesChanged(userID string, at time.Time) error
+	AddAuditEntry(e *types.AuditEntry) error
+	AuditEntries(q AuditQuery) ([]types.AuditEntry, error)
+	// Tiers
+	ListTiers() ([]types.Tier, error)
+	SetTiers(tiers []types.Tier) ([]types.Tier, error)
+	// Per-user grants
+	PostGrants(postID uint) ([]types.PostGrant, error)
+	ActiveGrant(postID uint, userID string, now time.Time) (*types.PostGrant, error)
+	SetPostGrant(g *types.PostGrant) error
+	DeletePostGrant(postID uint, userID string) error
+
+	// Share links
+	CreateShareLink(l *types.ShareLink) error
+	ShareLinkByToken(token string) (*types.ShareLink, error)
+	PostShareLinks(postID uint) ([]types.ShareLink, error)
+	UseShareLink(id uint, now time.Time) (bool, error)
+	RevokeShareLink(id uint, now time.Time) error
 }
 
 // sqliteDB is a gorm-backed implementation of DB.
 type sqliteDB struct {
-	db  *gorm.DB
-	ctx context.Context
-	mu  sync.RWMutex
+	db    *gorm.DB
+	ctx   context.Context
+	mu    sync.RWMutex
+	store blob.Store
+	// blobMu serializes writes that add or drop blob references so an object
+	// can't be freed between being reused and being committed. Take it before mu.
+	blobMu sync.Mutex
 }
 
-// Connect initializes the database, migrates the schema, and returns an implementation of DB.
+// Connect initializes the database, applies pending migrations, and returns an implementation of DB.
 // path can be "sqlite.db" or ":memory:" for in-memory mode.
-func Connect(path string, ctx context.Context) (DB, error) {
+// Media payloads are kept in store; only their keys and checksums live in SQLite.
+func Connect(path string, ctx context.Context, store blob.Store) (DB, error) {
+	if store == nil {
+		return nil, errors.New("blob store is required")
+	}
+
+	gdb, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := runMigrations(ctx, gdb.WithContext(ctx), store, false); err != nil {
+		return nil, err
+	}
+	// Ensure join table exists for many2many relation
+	if err := gdb.SetupJoinTable(&types.Post{}, "AllowedRoles", &struct{}{}); err != nil {
+		// Not strictly necessary; GORM will auto create. Keep for clarity.
+		_ = err
+	}
+
+	return &sqliteDB{db: gdb.WithContext(ctx), ctx: ctx, store: store}, nil
+}
+
+// open opens the SQLite database at path with the connection settings the
+// app relies on.
+func open(path string) (*gorm.DB, error) {
 	sqlDB, err := sql.Open("sqlite", path)
 	if err != nil {
 		return nil, err
@@ -74,32 +181,7 @@ func Connect(path string, ctx context.Context) (DB, error) {
 	// Enable WAL mode to allow concurrent reads and busy_timeout to queue concurrent writes
 	gdb.Exec("PRAGMA journal_mode=WAL;")
 	gdb.Exec("PRAGMA busy_timeout=3000;")
-
-	// Drop potential unique index on images.post_id to allow multiple images per post
-	_ = gdb.Exec("DROP INDEX IF EXISTS idx_images_post_id").Error
-
-	// Order matters for constraints/indexes and FKs;
-	// migrate base tables first, then Post, then child tables.
-	err = gdb.AutoMigrate(
-		&types.User{},
-		&types.Allowed{},
-		&types.Post{},
-		&types.Image{},
-		&types.ImageBlob{},
-		&types.Settings{},
-		&types.CachedRole{},
-		&types.CachedChannel{},
-	)
-	if err != nil {
-		return nil, err
-	}
-	// Ensure join table exists for many2many relation
-	if err := gdb.SetupJoinTable(&types.Post{}, "AllowedRoles", &struct{}{}); err != nil {
-		// Not strictly necessary; GORM will auto create. Keep for clarity.
-		_ = err
-	}
-
-	return &sqliteDB{db: gdb.WithContext(ctx), ctx: ctx}, nil
+	return gdb, nil
 }
 
 // Stop closes the database connection.
@@ -0,0 +1,189 @@
+package sqlite
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"drigo/pkg/types"
+)
+
+// ErrTagExists is returned when a tag name collides with an existing tag.
+var ErrTagExists = errors.New("tag already exists")
+
+// TagSlug normalizes a tag name: lowercase, with runs of anything other than
+// letters and digits collapsed to a single dash.
+func TagSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// tagSlugs returns the unique, non-empty slugs of names in order.
+func tagSlugs(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	slugs := make([]string, 0, len(names))
+	for _, name := range names {
+		slug := TagSlug(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		slugs = append(slugs, slug)
+	}
+	return slugs
+}
+
+// resolveTags returns the stored tags for tags, creating any that are missing.
+// Tags are matched by slug, so "Blue Eyes" and "blue-eyes" are the same tag.
+func resolveTags(tx *gorm.DB, tags []types.Tag) ([]types.Tag, error) {
+	resolved := make([]types.Tag, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		slug := TagSlug(t.Name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+
+		tag := types.Tag{Name: strings.TrimSpace(t.Name), Slug: slug}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("slug = ?", slug).First(&tag).Error; err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, tag)
+	}
+	return resolved, nil
+}
+
+// replacePostTags sets the tags of post p to p.Tags, creating new ones.
+func replacePostTags(tx *gorm.DB, p *types.Post) error {
+	tags, err := resolveTags(tx, p.Tags)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(p).Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+	p.Tags = tags
+	return nil
+}
+
+// reindexTagPosts refreshes the search documents of every post tagged id.
+func reindexTagPosts(tx *gorm.DB, id uint) error {
+	var postIDs []uint
+	if err := tx.Table("post_tags").Where("tag_id = ?", id).Pluck("post_id", &postIDs).Error; err != nil {
+		return err
+	}
+	for _, postID := range postIDs {
+		if err := reindexPost(tx, postID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListTags returns every tag by name with the number of published posts using it.
+func (s *sqliteDB) ListTags() ([]types.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var tags []types.Tag
+	err := s.db.Model(&types.Tag{}).
+		Select("tags.*, (SELECT COUNT(*) FROM post_tags pt JOIN posts p ON p.id = pt.post_id AND p.deleted_at IS NULL AND p.published_at IS NOT NULL AND p.archived_at IS NULL WHERE pt.tag_id = tags.id) AS post_count").
+		Order("name").
+		Find(&tags).Error
+	return tags, err
+}
+
+// CreateTag adds a tag, failing with ErrTagExists if its slug is taken.
+func (s *sqliteDB) CreateTag(name string) (*types.Tag, error) {
+	slug := TagSlug(name)
+	if slug == "" {
+		return nil, errors.New("tag name is empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tag := types.Tag{Name: strings.TrimSpace(name), Slug: slug}
+	res := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrTagExists
+	}
+	return &tag, nil
+}
+
+// RenameTag changes a tag's name and slug, and reindexes its posts.
+func (s *sqliteDB) RenameTag(id uint, name string) (*types.Tag, error) {
+	slug := TagSlug(name)
+	if slug == "" {
+		return nil, errors.New("tag name is empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tag types.Tag
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&tag, id).Error; err != nil {
+			return err
+		}
+		var taken int64
+		if err := tx.Model(&types.Tag{}).Where("slug = ? AND id <> ?", slug, id).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrTagExists
+		}
+		tag.Name, tag.Slug = strings.TrimSpace(name), slug
+		if err := tx.Save(&tag).Error; err != nil {
+			return err
+		}
+		return reindexTagPosts(tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// DeleteTag removes a tag from every post and deletes it.
+func (s *sqliteDB) DeleteTag(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var tag types.Tag
+		if err := tx.First(&tag, id).Error; err != nil {
+			return err
+		}
+		var postIDs []uint
+		if err := tx.Table("post_tags").Where("tag_id = ?", id).Pluck("post_id", &postIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM post_tags WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&tag).Error; err != nil {
+			return err
+		}
+		for _, postID := range postIDs {
+			if err := reindexPost(tx, postID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"drigo/pkg/types"
+)
+
+// ErrTierInUse is returned when removing a tier that posts still require.
+var ErrTierInUse = errors.New("tier is still required by posts")
+
+// ListTiers returns the tiers from lowest to highest rank.
+func (s *sqliteDB) ListTiers() ([]types.Tier, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var tiers []types.Tier
+	err := s.db.Order("rank").Find(&tiers).Error
+	return tiers, err
+}
+
+// SetTiers replaces the tier list with tiers, ordered from lowest to highest.
+// Tiers keep their ID while their role stays listed, so posts requiring them
+// follow the new ranks; removing a tier that posts require fails with
+// ErrTierInUse.
+func (s *sqliteDB) SetTiers(tiers []types.Tier) ([]types.Tier, error) {
+	roleIDs := make([]string, 0, len(tiers))
+	for _, t := range tiers {
+		if t.RoleID == "" {
+			return nil, errors.New("tier role is empty")
+		}
+		roleIDs = append(roleIDs, t.RoleID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var saved []types.Tier
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		removed := tx.Model(&types.Tier{}).Select("id")
+		if len(roleIDs) > 0 {
+			removed = removed.Where("role_id NOT IN ?", roleIDs)
+		}
+		var inUse int64
+		if err := tx.Model(&types.Post{}).Where("min_tier_id IN (?)", removed).Count(&inUse).Error; err != nil {
+			return err
+		}
+		if inUse > 0 {
+			return ErrTierInUse
+		}
+		if err := tx.Where("id IN (?)", removed).Delete(&types.Tier{}).Error; err != nil {
+			return err
+		}
+
+		for i, t := range tiers {
+			tier := types.Tier{RoleID: t.RoleID, Name: strings.TrimSpace(t.Name), Rank: i + 1}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "role_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"name", "rank", "updated_at"}),
+			}).Create(&tier).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Order("rank").Find(&saved).Error
+	})
+	return saved, err
+}
+
+// viewableClause restricts posts, aliased as table, to those userID holding
+// roleIDs may view. It mirrors access.Tiers.CanView under access.Grant: an
+// active deny hides the post, an active allow opens it, and otherwise posts
+// are open to everyone, past their early-access window, allow one of the
+// roles, or have a minimum tier or tier role at or below the member's highest
+// tier.
+func (s *sqliteDB) viewableClause(table, userID string, roleIDs []string) (string, []any) {
+	gated := s.db.Table("post_allowed_roles AS par").Select("1").
+		Joins("JOIN alloweds a ON a.id = par.allowed_id AND a.deleted_at IS NULL").
+		Where(fmt.Sprintf("par.post_id = %s.id", table))
+	held := gated.Session(&gorm.Session{}).Where("a.role_id IN ?", roleIDs)
+
+	allowedRoles := s.db.Table("post_allowed_roles AS par").Select("a.role_id").
+		Joins("JOIN alloweds a ON a.id = par.allowed_id AND a.deleted_at IS NULL").
+		Where(fmt.Sprintf("par.post_id = %s.id", table))
+	rank := s.db.Model(&types.Tier{}).Select("COALESCE(MAX(rank), 0)").Where("role_id IN ?", roleIDs)
+	ranked := s.db.Table("tiers AS t").Select("1").
+		Where("t.rank <= (?)", rank).
+		Where(fmt.Sprintf("t.id = %s.min_tier_id OR t.role_id IN (?)", table), allowedRoles)
+
+	grant := s.db.Table("post_grants AS g").Select("1").
+		Where(fmt.Sprintf("g.post_id = %s.id", table)).
+		Where("g.user_id = ?", userID).
+		Where("g.expires_at IS NULL OR g.expires_at > ?", time.Now().UTC())
+	denied := grant.Session(&gorm.Session{}).Where("g.effect = ?", types.GrantDeny)
+	allowed := grant.Session(&gorm.Session{}).Where("g.effect = ?", types.GrantAllow)
+
+	query := fmt.Sprintf("NOT EXISTS (?) AND (EXISTS (?) OR (NOT EXISTS (?) AND %[1]s.min_tier_id IS NULL) OR %[1]s.public_after <= ? OR EXISTS (?) OR EXISTS (?))", table)
+	return query, []any{denied, allowed, gated, time.Now().UTC(), held, ranked}
+}
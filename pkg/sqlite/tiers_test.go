@@ -0,0 +1,131 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"drigo/pkg/access"
+	"drigo/pkg/blob"
+	"drigo/pkg/types"
+)
+
+// TestViewableMatchesAccess checks that the SQL behind ListPosts{Viewable}
+// agrees with access.Tiers.CanView under access.Grant for every viewer.
+func TestViewableMatchesAccess(t *testing.T) {
+	t.Parallel()
+
+	store, err := blob.NewLocal(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := Connect(filepath.Join(t.TempDir(), "sqlite.db"), context.Background(), store)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Stop()
+
+	tiers, err := conn.SetTiers([]types.Tier{{RoleID: "bronze"}, {RoleID: "silver"}, {RoleID: "gold"}})
+	if err != nil {
+		t.Fatalf("set tiers: %v", err)
+	}
+	tierID := make(map[string]*uint, len(tiers))
+	for _, tier := range tiers {
+		tierID[tier.RoleID] = &tier.ID
+	}
+
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	roles := func(ids ...string) []types.Allowed {
+		var allowed []types.Allowed
+		for _, id := range ids {
+			allowed = append(allowed, types.Allowed{RoleID: id})
+		}
+		return allowed
+	}
+	posts := []*types.Post{
+		{PostKey: "open"},
+		{PostKey: "fan", AllowedRoles: roles("fan")},
+		{PostKey: "fan-or-vip", AllowedRoles: roles("fan", "vip")},
+		{PostKey: "bronze-role", AllowedRoles: roles("bronze")},
+		{PostKey: "silver-role", AllowedRoles: roles("silver")},
+		{PostKey: "min-silver", MinTierID: tierID["silver"]},
+		{PostKey: "min-gold-or-fan", MinTierID: tierID["gold"], AllowedRoles: roles("fan")},
+		{PostKey: "vip-early", AllowedRoles: roles("vip"), PublicAfter: &future},
+		{PostKey: "vip-public", AllowedRoles: roles("vip"), PublicAfter: &past},
+		{PostKey: "gold-public", MinTierID: tierID["gold"], PublicAfter: &past},
+	}
+	byKey := make(map[string]*types.Post, len(posts))
+	for _, p := range posts {
+		p.Title = p.PostKey
+		if err := conn.CreatePost(p); err != nil {
+			t.Fatalf("create %s: %v", p.PostKey, err)
+		}
+		byKey[p.PostKey] = p
+	}
+
+	grants := []types.PostGrant{
+		{PostID: byKey["open"].ID, UserID: "member", Effect: types.GrantDeny},
+		{PostID: byKey["min-gold-or-fan"].ID, UserID: "member", Effect: types.GrantAllow},
+		{PostID: byKey["vip-early"].ID, UserID: "member", Effect: types.GrantAllow},
+		{PostID: byKey["fan"].ID, UserID: "member", Effect: types.GrantDeny, ExpiresAt: &past},
+		{PostID: byKey["silver-role"].ID, UserID: "member", Effect: types.GrantDeny, ExpiresAt: &future},
+		{PostID: byKey["min-silver"].ID, UserID: "other", Effect: types.GrantAllow},
+	}
+	for _, g := range grants {
+		if err := conn.SetPostGrant(&g); err != nil {
+			t.Fatalf("set grant: %v", err)
+		}
+	}
+
+	checker := access.NewTiers(tiers)
+	tests := []struct {
+		name    string
+		userID  string
+		roleIDs []string
+	}{
+		{name: "anonymous"},
+		{name: "no roles", userID: "nobody"},
+		{name: "fan", userID: "nobody", roleIDs: []string{"fan"}},
+		{name: "vip", userID: "nobody", roleIDs: []string{"vip"}},
+		{name: "bronze", userID: "nobody", roleIDs: []string{"bronze"}},
+		{name: "silver", userID: "nobody", roleIDs: []string{"silver"}},
+		{name: "gold", userID: "nobody", roleIDs: []string{"gold", "unrelated"}},
+		{name: "member with grants", userID: "member"},
+		{name: "member with grants and fan", userID: "member", roleIDs: []string{"fan"}},
+		{name: "member with grants and gold", userID: "member", roleIDs: []string{"gold"}},
+		{name: "other with grant", userID: "other", roleIDs: []string{"bronze"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []string
+			for _, p := range posts {
+				var g *types.PostGrant
+				if tt.userID != "" {
+					if g, err = conn.ActiveGrant(p.ID, tt.userID, time.Now()); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if access.Grant(g, checker.CanView(tt.roleIDs, p)) {
+					want = append(want, p.PostKey)
+				}
+			}
+
+			listed, err := conn.ListPosts(ListOptions{Limit: len(posts), Viewable: true, UserID: tt.userID, RoleIDs: tt.roleIDs})
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			var got []string
+			for _, p := range listed {
+				got = append(got, p.PostKey)
+			}
+
+			slices.Sort(want)
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Fatalf("ListPosts = %v, access allows %v", got, want)
+			}
+		})
+	}
+}
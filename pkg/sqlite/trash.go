@@ -0,0 +1,360 @@
+package sqlite
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"drigo/pkg/types"
+)
+
+var (
+	// ErrNotTrashed is returned when restoring or purging something that
+	// isn't in the trash.
+	ErrNotTrashed = errors.New("not in the trash")
+	// ErrPostTrashed is returned when restoring media of a post that is
+	// itself in the trash; restore the post instead.
+	ErrPostTrashed = errors.New("post is in the trash")
+)
+
+// retireImages removes the current images of a post before next replaces
+// them. Images whose blobs all carry over to next are dropped outright; the
+// rest are moved to the trash with their blobs, keeping their payloads until
+// they are purged.
+func retireImages(tx *gorm.DB, postID uint, next []types.Image) error {
+	kept := make(map[string]bool)
+	for _, img := range next {
+		for _, b := range img.Blobs {
+			kept[b.Checksum] = true
+		}
+	}
+
+	var imgs []types.Image
+	if err := tx.Preload("Blobs").Where("post_id = ?", postID).Find(&imgs).Error; err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, img := range imgs {
+		replaced := slices.ContainsFunc(img.Blobs, func(b types.ImageBlob) bool { return !kept[b.Checksum] })
+		if replaced {
+			if err := trashImage(tx, img.ID, now); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Unscoped().Where("image_id = ?", img.ID).Delete(&types.ImageBlob{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&types.Image{}, img.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trashImage soft-deletes an image and its live blobs at now.
+func trashImage(tx *gorm.DB, id uint, now time.Time) error {
+	if err := tx.Model(&types.ImageBlob{}).Where("image_id = ?", id).UpdateColumn("deleted_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&types.Image{}).Where("id = ?", id).UpdateColumn("deleted_at", now).Error
+}
+
+// trashPreloads loads what listPreloads does, but with the images and blobs
+// that went to the trash with the post.
+func trashPreloads(db *gorm.DB) *gorm.DB {
+	return db.Unscoped().
+		Preload("Author").
+		Preload("Images", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped().
+				Select("id", "created_at", "updated_at", "deleted_at", "post_id", "thumbnail_key", "thumbnail_checksum", "thumbnail_blurred", "(CASE WHEN thumbnail_key <> '' THEN 1 ELSE 0 END) as has_thumbnail").
+				Where("deleted_at = (SELECT p.deleted_at FROM posts p WHERE p.id = images.post_id)")
+		}).
+		Preload("Images.Blobs", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped().
+				Select("id", "created_at", "updated_at", "deleted_at", "image_id", "index", "storage_key", "checksum", "content_type", "filename", "size")
+		}).
+		Preload("AllowedRoles").
+		Preload("Tags")
+}
+
+// TrashedPosts returns deleted posts, most recently deleted first.
+func (s *sqliteDB) TrashedPosts(limit, offset int) ([]*types.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var posts []*types.Post
+	err := trashPreloads(s.db).
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&posts).Error
+	return posts, err
+}
+
+// TrashedMedia returns images replaced or removed from posts that are still
+// live, most recently trashed first.
+func (s *sqliteDB) TrashedMedia(limit, offset int) ([]types.Image, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var imgs []types.Image
+	err := s.db.Unscoped().
+		Preload("Blobs", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped().
+				Select("id", "created_at", "updated_at", "deleted_at", "image_id", "index", "storage_key", "checksum", "content_type", "filename", "size")
+		}).
+		Select("id", "created_at", "updated_at", "deleted_at", "post_id", "thumbnail_key", "thumbnail_checksum", "thumbnail_blurred", "(CASE WHEN thumbnail_key <> '' THEN 1 ELSE 0 END) as has_thumbnail").
+		Where("deleted_at IS NOT NULL AND post_id IN (?)", s.db.Model(&types.Post{}).Select("id")).
+		Order("deleted_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&imgs).Error
+	return imgs, err
+}
+
+// RestoreTrashedPost brings a deleted post back with the images and blobs
+// that were trashed with it. Media replaced before the post was deleted stays
+// in the trash.
+func (s *sqliteDB) RestoreTrashedPost(id uint) (*types.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var p types.Post
+		if err := tx.Unscoped().Select("id", "deleted_at").First(&p, id).Error; err != nil {
+			return err
+		}
+		if !p.DeletedAt.Valid {
+			return ErrNotTrashed
+		}
+
+		deletedAt := tx.Unscoped().Model(&types.Post{}).Select("deleted_at").Where("id = ?", id)
+		imageIDs := tx.Unscoped().Model(&types.Image{}).Select("id").Where("post_id = ? AND deleted_at = (?)", id, deletedAt)
+		if err := tx.Unscoped().Model(&types.ImageBlob{}).
+			Where("image_id IN (?) AND deleted_at = (?)", imageIDs, deletedAt).
+			UpdateColumn("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&types.Image{}).
+			Where("post_id = ? AND deleted_at = (?)", id, deletedAt).
+			UpdateColumn("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&types.Post{}).Where("id = ?", id).UpdateColumn("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return reindexPost(tx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var p types.Post
+	if err := listPreloads(s.db).First(&p, id).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// RestoreTrashedMedia puts a trashed image back on its post, in the place it
+// had before it was replaced, and returns the post.
+func (s *sqliteDB) RestoreTrashedMedia(id uint) (*types.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var img types.Image
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Select("id", "deleted_at", "post_id").First(&img, id).Error; err != nil {
+			return err
+		}
+		if !img.DeletedAt.Valid {
+			return ErrNotTrashed
+		}
+		var live int64
+		if err := tx.Model(&types.Post{}).Where("id = ?", img.PostID).Count(&live).Error; err != nil {
+			return err
+		}
+		if live == 0 {
+			return ErrPostTrashed
+		}
+
+		if err := tx.Unscoped().Model(&types.ImageBlob{}).Where("image_id = ?", id).UpdateColumn("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&types.Image{}).Where("id = ?", id).UpdateColumn("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return reindexPost(tx, img.PostID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var p types.Post
+	if err := listPreloads(s.db).First(&p, img.PostID).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// PurgePost permanently removes a trashed post with everything attached to it
+// and frees payloads no longer referenced.
+func (s *sqliteDB) PurgePost(id uint) error {
+	s.blobMu.Lock()
+	defer s.blobMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var touched []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var p types.Post
+		if err := tx.Unscoped().Select("id", "deleted_at").First(&p, id).Error; err != nil {
+			return err
+		}
+		if !p.DeletedAt.Valid {
+			return ErrNotTrashed
+		}
+		var err error
+		touched, err = purgePost(tx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.release(touched)
+	return nil
+}
+
+// PurgeMedia permanently removes a trashed image and frees payloads no longer
+// referenced.
+func (s *sqliteDB) PurgeMedia(id uint) error {
+	s.blobMu.Lock()
+	defer s.blobMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var touched []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var img types.Image
+		if err := tx.Unscoped().Select("id", "deleted_at").First(&img, id).Error; err != nil {
+			return err
+		}
+		if !img.DeletedAt.Valid {
+			return ErrNotTrashed
+		}
+		var err error
+		touched, err = purgeImage(tx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.release(touched)
+	return nil
+}
+
+// PurgeTrash permanently removes posts and media trashed before the given
+// time and returns how many were removed.
+func (s *sqliteDB) PurgeTrash(before time.Time) (int, error) {
+	before = before.UTC()
+	s.blobMu.Lock()
+	defer s.blobMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var touched []string
+	var n int
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var postIDs []uint
+		if err := tx.Unscoped().Model(&types.Post{}).
+			Where("deleted_at < ?", before).
+			Pluck("id", &postIDs).Error; err != nil {
+			return err
+		}
+		var imgs []types.Image
+		if err := tx.Unscoped().Select("id", "post_id").
+			Where("deleted_at < ?", before).
+			Find(&imgs).Error; err != nil {
+			return err
+		}
+
+		for _, img := range imgs {
+			if slices.Contains(postIDs, img.PostID) {
+				continue
+			}
+			sums, err := purgeImage(tx, img.ID)
+			if err != nil {
+				return err
+			}
+			touched = append(touched, sums...)
+			n++
+		}
+		for _, id := range postIDs {
+			sums, err := purgePost(tx, id)
+			if err != nil {
+				return err
+			}
+			touched = append(touched, sums...)
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.release(touched)
+	return n, nil
+}
+
+// purgePost hard-deletes a post and every row that refers to it, returning
+// the checksums it held.
+func purgePost(tx *gorm.DB, id uint) ([]string, error) {
+	sums, err := postChecksums(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	imageIDs := tx.Unscoped().Model(&types.Image{}).Select("id").Where("post_id = ?", id)
+	if err := tx.Unscoped().Where("image_id IN (?)", imageIDs).Delete(&types.ImageBlob{}).Error; err != nil {
+		return nil, err
+	}
+	for _, model := range []any{
+		&types.Image{},
+		&types.Download{},
+		&types.PostGrant{},
+		&types.ShareLink{},
+		&types.CollectionItem{},
+		&types.PostMessage{},
+	} {
+		if err := tx.Unscoped().Where("post_id = ?", id).Delete(model).Error; err != nil {
+			return nil, err
+		}
+	}
+	for _, table := range []string{"post_allowed_roles", "post_tags"} {
+		if err := tx.Exec("DELETE FROM "+table+" WHERE post_id = ?", id).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Unscoped().Delete(&types.Post{}, id).Error; err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
+
+// purgeImage hard-deletes an image and its blobs, returning the checksums it
+// held.
+func purgeImage(tx *gorm.DB, id uint) ([]string, error) {
+	var img types.Image
+	if err := tx.Unscoped().Preload("Blobs", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(&img, id).Error; err != nil {
+		return nil, err
+	}
+	sums := []string{img.ThumbnailChecksum}
+	for _, b := range img.Blobs {
+		sums = append(sums, b.Checksum)
+	}
+	if err := tx.Unscoped().Where("image_id = ?", id).Delete(&types.ImageBlob{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Delete(&types.Image{}, id).Error; err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"drigo/pkg/types"
+)
+
+func (s *sqliteDB) CreateUpload(u *types.Upload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Create(u).Error
+}
+
+// GetUpload returns the upload with id, or nil if it doesn't exist.
+func (s *sqliteDB) GetUpload(id string) (*types.Upload, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var u types.Upload
+	if err := s.db.Where("id = ?", id).First(&u).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// SaveUploadProgress persists the offset, hash state and completion fields of u.
+func (s *sqliteDB) SaveUploadProgress(u *types.Upload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Model(&types.Upload{}).Where("id = ?", u.ID).
+		Select("upload_offset", "hash_state", "checksum", "content_type", "expires_at", "updated_at").
+		Updates(u).Error
+}
+
+func (s *sqliteDB) DeleteUpload(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Where("id = ?", id).Delete(&types.Upload{}).Error
+}
+
+// ExpiredUploads lists uploads whose expiry is before now.
+func (s *sqliteDB) ExpiredUploads(now time.Time) ([]types.Upload, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var uploads []types.Upload
+	err := s.db.Where("expires_at < ?", now).Find(&uploads).Error
+	return uploads, err
+}
@@ -0,0 +1,228 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// Audit actions.
+const (
+	// AuditAccessLost is recorded when a member loses access to a post they
+	// downloaded, through a role change or leaving the guild.
+	AuditAccessLost = "member.access_lost"
+	// AuditShareUse is recorded each time a share link opens a post.
+	AuditShareUse = "share.use"
+
+	AuditLogin     = "user.login"
+	AuditUserAdmin = "user.admin"
+	AuditSettings  = "settings.update"
+
+	AuditPostCreate     = "post.create"
+	AuditPostUpdate     = "post.update"
+	AuditPostDelete     = "post.delete"
+	AuditPostPublish    = "post.publish"
+	AuditPostSchedule   = "post.schedule"
+	AuditPostUnschedule = "post.unschedule"
+	AuditPostRestore    = "post.restore"
+	AuditPostDM         = "post.dm"
+
+	AuditGrantSet    = "grant.set"
+	AuditGrantDelete = "grant.delete"
+	AuditShareCreate = "share.create"
+	AuditShareRevoke = "share.revoke"
+
+	AuditTagCreate = "tag.create"
+	AuditTagUpdate = "tag.update"
+	AuditTagDelete = "tag.delete"
+	AuditTiers     = "tiers.update"
+
+	AuditCollectionCreate = "collection.create"
+	AuditCollectionUpdate = "collection.update"
+	AuditCollectionDelete = "collection.delete"
+
+	AuditTrashRestore = "trash.restore"
+	AuditTrashPurge   = "trash.purge"
+)
+
+// Audit sources, telling where an action came from.
+const (
+	AuditSourceAPI     = "api"
+	AuditSourceDiscord = "discord"
+	AuditSourceSystem  = "system"
+)
+
+// AuditEntry is an append-only record of an event worth reviewing later.
+// ActorID is empty for events raised by the system. Changes holds the fields
+// an action modified; Detail any other context as JSON.
+type AuditEntry struct {
+	ID         uint                   `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time              `gorm:"index" json:"createdAt"`
+	ActorID    string                 `gorm:"index;size:32" json:"actorId"`
+	ActorName  string                 `gorm:"size:64" json:"actorName"`
+	Action     string                 `gorm:"index;size:64" json:"action"`
+	TargetType string                 `gorm:"index:idx_audit_target;size:32" json:"targetType"`
+	TargetID   string                 `gorm:"index:idx_audit_target;size:64" json:"targetId"`
+	Changes    map[string]AuditChange `gorm:"serializer:json" json:"changes,omitempty"`
+	Detail     string                 `json:"detail"`
+
+	// Request metadata. Method and Path describe the HTTP request; for
+	// actions taken in Discord, Path is the command and IP and Method are
+	// empty.
+	Source    string `gorm:"size:16" json:"source"`
+	IP        string `gorm:"size:64" json:"ip,omitempty"`
+	UserAgent string `json:"userAgent,omitempty"`
+	Method    string `gorm:"size:8" json:"method,omitempty"`
+	Path      string `json:"path,omitempty"`
+}
+
+// AuditChange is the value of a field before and after a change.
+type AuditChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// AuditDiff returns the fields whose values differ between two snapshots of
+// the same object, or nil if none do. A field missing from one side is
+// recorded as nil there, and empty values are all treated as equal, so a nil
+// snapshot stands for an object being created or deleted.
+func AuditDiff(before, after map[string]any) map[string]AuditChange {
+	var changes map[string]AuditChange
+	add := func(k string) {
+		if reflect.DeepEqual(before[k], after[k]) || (auditEmpty(before[k]) && auditEmpty(after[k])) {
+			return
+		}
+		if changes == nil {
+			changes = make(map[string]AuditChange)
+		}
+		changes[k] = AuditChange{From: before[k], To: after[k]}
+	}
+	for k := range before {
+		add(k)
+	}
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			add(k)
+		}
+	}
+	return changes
+}
+
+// AuditFields returns the fields of a post recorded in audit diffs. Relations
+// are reduced to sorted IDs, and media to their checksums in order.
+func (p *Post) AuditFields() map[string]any {
+	if p == nil {
+		return nil
+	}
+	roles := make([]string, 0, len(p.AllowedRoles))
+	for _, r := range p.AllowedRoles {
+		roles = append(roles, r.RoleID)
+	}
+	slices.Sort(roles)
+	tags := make([]string, 0, len(p.Tags))
+	for _, t := range p.Tags {
+		tags = append(tags, t.Slug)
+	}
+	slices.Sort(tags)
+	media := make([]string, 0, len(p.Images))
+	for _, img := range p.Images {
+		for _, b := range img.Blobs {
+			media = append(media, b.Checksum)
+		}
+	}
+	var minTier any
+	if p.MinTierID != nil {
+		minTier = *p.MinTierID
+	}
+
+	return map[string]any{
+		"title":               p.Title,
+		"description":         p.Description,
+		"isPremium":           p.IsPremium,
+		"draft":               p.Draft,
+		"publishAt":           AuditTime(p.PublishAt),
+		"publicAfter":         AuditTime(p.PublicAfter),
+		"announcePublic":      p.AnnouncePublic,
+		"expiresAt":           AuditTime(p.ExpiresAt),
+		"expireAnnouncements": p.ExpireAnnouncements,
+		"allowedRoles":        roles,
+		"minTierId":           minTier,
+		"tags":                tags,
+		"media":               media,
+	}
+}
+
+// AuditFields returns the fields of a collection recorded in audit diffs.
+// Roles inherited from its posts are left out, and posts are listed by key in
+// order.
+func (c *Collection) AuditFields() map[string]any {
+	if c == nil {
+		return nil
+	}
+	roles := make([]string, 0, len(c.AllowedRoles))
+	if !c.InheritedRoles {
+		for _, r := range c.AllowedRoles {
+			roles = append(roles, r.RoleID)
+		}
+		slices.Sort(roles)
+	}
+	posts := make([]string, 0, len(c.Posts))
+	for _, p := range c.Posts {
+		posts = append(posts, p.PostKey)
+	}
+	return map[string]any{
+		"title":        c.Title,
+		"description":  c.Description,
+		"allowedRoles": roles,
+		"posts":        posts,
+	}
+}
+
+// AuditFields returns the settings recorded in audit diffs, with the theme
+// flattened into "theme.*" fields.
+func (s *Settings) AuditFields() map[string]any {
+	if s == nil {
+		return nil
+	}
+	fields := map[string]any{
+		"hero_title":       auditString(s.HeroTitle),
+		"hero_subtitle":    auditString(s.HeroSubtitle),
+		"hero_description": auditString(s.HeroDescription),
+		"public_access":    s.PublicAccess,
+	}
+	var theme map[string]any
+	if data, err := json.Marshal(s.Theme); err == nil && json.Unmarshal(data, &theme) == nil {
+		for k, v := range theme {
+			fields["theme."+k] = v
+		}
+	}
+	return fields
+}
+
+func auditEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
+
+// AuditTime formats an optional time as audit entries record it, in UTC.
+func AuditTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func auditString(s *string) any {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
@@ -0,0 +1,15 @@
+package types
+
+import "time"
+
+// BlobObject is a content-addressed payload in the blob store. Identical
+// uploads share one object; RefCount tracks how many images and blobs point
+// at it, trashed ones included, and the object is removed when it drops to
+// zero.
+type BlobObject struct {
+	Checksum   string    `gorm:"primaryKey" json:"checksum"` // hex SHA-256
+	StorageKey string    `json:"-"`
+	Size       int64     `json:"size"`
+	RefCount   int64     `json:"refCount"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -0,0 +1,47 @@
+package types
+
+import "gorm.io/gorm"
+
+// Collection is an ordered series of posts, such as a multi-part comic.
+type Collection struct {
+	gorm.Model
+
+	Title       string `gorm:"type:text" json:"title"`
+	Description string `gorm:"type:text" json:"description"`
+
+	// Many-to-many; join table: collection_allowed_roles. When empty, the
+	// collection is gated by the union of its posts' roles instead.
+	AllowedRoles []Allowed `gorm:"many2many:collection_allowed_roles" json:"allowedRoles"`
+	// InheritedRoles reports that AllowedRoles was derived from the posts.
+	InheritedRoles bool `gorm:"-" json:"inheritedRoles"`
+
+	// Items is the ordered membership; Posts holds the live posts in that order.
+	Items []CollectionItem `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Posts []*Post          `gorm:"-" json:"posts"`
+}
+
+// CollectionItem places a post in a collection. A post appears at most once
+// per collection but may belong to several.
+type CollectionItem struct {
+	ID           uint `gorm:"primarykey" json:"id"`
+	CollectionID uint `gorm:"uniqueIndex:idx_collection_post" json:"collectionId"`
+	PostID       uint `gorm:"uniqueIndex:idx_collection_post;index" json:"postId"`
+	Position     int  `json:"position"`
+}
+
+// CollectionNav locates a post within one of its collections.
+type CollectionNav struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+	// Position is 1-based among the collection's live posts.
+	Position int      `json:"position"`
+	Total    int      `json:"total"`
+	Prev     *PostRef `json:"prev"`
+	Next     *PostRef `json:"next"`
+}
+
+// PostRef is just enough of a post to link to it.
+type PostRef struct {
+	PostKey string `json:"postKey"`
+	Title   string `json:"title"`
+}
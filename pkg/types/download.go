@@ -0,0 +1,13 @@
+package types
+
+import "time"
+
+// Download records that a user fetched the full media of a post, so losing
+// access to it later can be audited.
+type Download struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    string    `gorm:"uniqueIndex:idx_download_user_post;size:32" json:"userId"`
+	PostID    uint      `gorm:"uniqueIndex:idx_download_user_post;index" json:"postId"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
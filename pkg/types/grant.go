@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+// Grant effects.
+const (
+	GrantAllow = "allow"
+	GrantDeny  = "deny"
+)
+
+// PostGrant opens a post to, or blocks it from, one Discord user regardless
+// of their roles. A deny wins over everything but admin rights.
+type PostGrant struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	PostID    uint       `gorm:"uniqueIndex:idx_grant_post_user" json:"postId"`
+	UserID    string     `gorm:"uniqueIndex:idx_grant_post_user;size:32" json:"userId"`
+	Effect    string     `gorm:"size:8" json:"effect"`
+	ExpiresAt *time.Time `gorm:"index" json:"expiresAt"`
+	GrantedBy string     `gorm:"size:32" json:"grantedBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// Active reports whether the grant still applies at now.
+func (g *PostGrant) Active(now time.Time) bool {
+	return g.ExpiresAt == nil || now.Before(*g.ExpiresAt)
+}
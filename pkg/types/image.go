@@ -16,8 +16,16 @@ type Image struct {
 	gorm.Model
 
 	// Enforce one Image per Post via unique index on PostID
-	PostID    uint   `gorm:"index" json:"postId"`
-	Thumbnail []byte `gorm:"type:blob" json:"thumbnail"`
+	PostID uint `gorm:"index" json:"postId"`
+
+	// Thumbnail holds the payload in memory only; it is persisted in the blob
+	// store under ThumbnailKey.
+	Thumbnail         []byte `gorm:"-" json:"thumbnail"`
+	ThumbnailKey      string `gorm:"index" json:"-"`
+	ThumbnailChecksum string `gorm:"index" json:"thumbnailChecksum,omitempty"`
+	// ThumbnailBlurred marks a thumbnail generated blurred for a gated post,
+	// to be regenerated once the post goes public.
+	ThumbnailBlurred bool `json:"thumbnailBlurred"`
 
 	// Computed field
 	HasThumbnail *bool `gorm:"->;type:boolean" json:"hasThumbnail"`
@@ -31,10 +39,16 @@ type ImageBlob struct {
 	// Enforce unique order for blobs per image
 	ImageID     uint   `gorm:"index;uniqueIndex:idx_image_blob_order" json:"imageId"`
 	Index       int    `gorm:"index;uniqueIndex:idx_image_blob_order" json:"index"` // stable order
-	Data        []byte `gorm:"type:blob" json:"data"`
+	Data        []byte `gorm:"-" json:"data"`                                       // loaded from the blob store on demand
+	StorageKey  string `gorm:"index" json:"-"`
+	Checksum    string `gorm:"index" json:"checksum,omitempty"` // hex SHA-256 of Data
 	ContentType string `json:"contentType"`
 	Size        int64  `json:"size"`
 	Filename    string `json:"filename"`
+
+	// Source streams a new payload into the blob store instead of Data. It
+	// requires Checksum and Size to be set by whoever produced the stream.
+	Source io.Reader `gorm:"-" json:"-"`
 }
 
 func (im *Image) GetImages() (thumb []byte, imgs [][]byte) {
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// PendingPost holds what /post collected until the author submits its modal.
+// It is stored in the database, with its media in the blob store, so an
+// in-flight post survives a restart; abandoned ones are dropped after ExpiresAt.
+type PendingPost struct {
+	PostKey     string          `gorm:"primaryKey;size:32" json:"postKey"`
+	GuildID     string          `json:"guildId"`
+	ChannelID   string          `json:"channelId"`
+	Author      *discordgo.User `gorm:"serializer:json" json:"author"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	// NeedsThumbnail is set when no thumbnail was provided; the modal then asks for one.
+	NeedsThumbnail bool   `json:"needsThumbnail"`
+	S3ThumbURL     string `json:"s3ThumbUrl"`
+	// PostedAt is the original time of an imported message; zero means the submit time.
+	PostedAt time.Time `json:"postedAt"`
+
+	Thumbnail    []byte `gorm:"-" json:"-"`
+	ThumbnailKey string `json:"-"`
+
+	Media []PendingMedia `gorm:"foreignKey:PostKey;references:PostKey" json:"media"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `gorm:"index" json:"expiresAt"`
+}
+
+// PendingMedia is one attachment of a PendingPost. Data is kept in the blob
+// store under StorageKey and only loaded in memory while the post is in use.
+// Media imported from a Discord message is fetched from SourceURL on submit
+// instead.
+type PendingMedia struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	PostKey     string `gorm:"index;size:32" json:"postKey"`
+	Index       int    `json:"index"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	StorageKey  string `json:"-"`
+	SourceURL   string `json:"-"`
+	Data        []byte `gorm:"-" json:"-"`
+}
@@ -36,12 +36,51 @@ type Post struct {
 	FocusX      *float64  `gorm:"default:50" json:"focusX"`
 	FocusY      *float64  `gorm:"default:50" json:"focusY"`
 
+	// PublishAt is when a scheduled post goes live; PublishedAt is set once it
+	// has. Posts without PublishedAt are hidden from everyone but admins.
+	PublishAt   *time.Time `gorm:"index" json:"publishAt"`
+	PublishedAt *time.Time `gorm:"index" json:"publishedAt"`
+	// Draft posts are unpublished and unscheduled; they go live only when
+	// published or scheduled explicitly.
+	Draft bool `gorm:"index" json:"draft"`
+
+	// PublicAfter ends the early-access window of a gated post: from then on
+	// everyone may view it. WentPublicAt is set once the window has opened and
+	// the post was re-announced; AnnouncePublic adds a second announcement then.
+	PublicAfter    *time.Time `gorm:"index" json:"publicAfter"`
+	AnnouncePublic bool       `json:"announcePublic"`
+	WentPublicAt   *time.Time `json:"wentPublicAt"`
+
+	// ExpiresAt is when the post is archived: hidden from the gallery and its
+	// media blocked for everyone but admins until restored. ArchivedAt is set
+	// once it has been; ExpireAnnouncements also deletes its Discord messages.
+	ExpiresAt           *time.Time `gorm:"index" json:"expiresAt"`
+	ArchivedAt          *time.Time `gorm:"index" json:"archivedAt"`
+	ExpireAnnouncements bool       `json:"expireAnnouncements"`
+
 	AuthorID uint  `gorm:"index;default:null" json:"authorId"` // FK to User (optional)
 	Author   *User `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"author"`
 
 	// Many-to-many; join table: post_allowed_roles
 	AllowedRoles []Allowed `gorm:"many2many:post_allowed_roles" json:"allowedRoles"`
+	// MinTierID, when set, also opens the post to members at or above that tier.
+	MinTierID *uint `gorm:"index" json:"minTierId"`
+
+	// Many-to-many; join table: post_tags
+	Tags []Tag `gorm:"many2many:post_tags" json:"tags"`
 
 	// One-to-many images payload
 	Images []Image `gorm:"constraint:OnDelete:CASCADE" json:"images"`
+
+	// Snippet is the highlighted search match, set only on search results.
+	Snippet string `gorm:"-" json:"snippet,omitempty"`
+	// Collections locates the post in each series it belongs to, set only
+	// when reading a single post.
+	Collections []CollectionNav `gorm:"-" json:"collections,omitempty"`
+}
+
+// Visible reports whether the post is live for non-admins: published and not
+// archived.
+func (p *Post) Visible() bool {
+	return p.PublishedAt != nil && p.ArchivedAt == nil
 }
@@ -0,0 +1,13 @@
+package types
+
+import "time"
+
+// PostMessage records a Discord message announcing a post, so it can be
+// edited when the post changes and removed when it is deleted.
+type PostMessage struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	PostID    uint      `gorm:"index" json:"postId"`
+	ChannelID string    `gorm:"size:32" json:"channelId"`
+	MessageID string    `gorm:"uniqueIndex;size:32" json:"messageId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,44 @@
+package types
+
+import (
+	"slices"
+	"time"
+)
+
+// ShareLink lets anyone holding Token open one post without signing in,
+// until it expires, runs out of uses or is revoked.
+type ShareLink struct {
+	ID      uint   `gorm:"primarykey" json:"id"`
+	Token   string `gorm:"uniqueIndex;size:96" json:"token"`
+	PostID  uint   `gorm:"index" json:"postId"`
+	PostKey string `gorm:"size:32" json:"postKey"`
+
+	ExpiresAt time.Time `gorm:"index" json:"expiresAt"`
+	// MaxUses caps how often the post can be opened; 0 means unlimited.
+	MaxUses int `json:"maxUses"`
+	Uses    int `json:"uses"`
+	// Media lists the checksums of the media the link exposes; empty means
+	// all of them. Checksums survive edits that reorder or replace media, so
+	// a link never starts exposing media it wasn't created for.
+	Media []string `gorm:"column:media_checksums;serializer:json" json:"media"`
+
+	CreatedBy string     `gorm:"size:32" json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt"`
+}
+
+// Live reports whether the link still grants access at now, ignoring uses.
+func (l *ShareLink) Live(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
+
+// AllowsMedia reports whether the media stored under checksum is exposed by
+// the link.
+func (l *ShareLink) AllowsMedia(checksum string) bool {
+	return len(l.Media) == 0 || slices.Contains(l.Media, checksum)
+}
+
+// AllowsImage reports whether any of img's blobs is exposed by the link.
+func (l *ShareLink) AllowsImage(img Image) bool {
+	return slices.ContainsFunc(img.Blobs, func(b ImageBlob) bool { return l.AllowsMedia(b.Checksum) })
+}
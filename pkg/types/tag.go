@@ -0,0 +1,16 @@
+package types
+
+import "time"
+
+// Tag groups posts by character, series, medium and so on. Slug is the
+// normalized name used for lookups and filtering.
+type Tag struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Name      string    `gorm:"size:64" json:"name"`
+	Slug      string    `gorm:"uniqueIndex;size:64" json:"slug"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// PostCount is the number of live posts with this tag, set by ListTags.
+	PostCount int64 `gorm:"->;-:migration" json:"postCount"`
+}
@@ -0,0 +1,14 @@
+package types
+
+import "time"
+
+// Tier ranks a Discord role. A member's rank is the highest among their
+// roles, and posts gated to a tier open to every member at or above it.
+type Tier struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	RoleID    string    `gorm:"uniqueIndex;size:32" json:"roleId"`
+	Name      string    `gorm:"size:64" json:"name"`
+	Rank      int       `gorm:"index" json:"rank"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
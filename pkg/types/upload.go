@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+// Upload is a resumable (tus) upload. Its bytes are appended to a file in the
+// server's upload directory until Offset reaches Length, after which it can be
+// attached to a post by ID.
+type Upload struct {
+	ID          string `gorm:"primaryKey" json:"id"`
+	UserID      string `gorm:"index" json:"userId"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Length      int64  `json:"length"`
+	Offset      int64  `gorm:"column:upload_offset" json:"offset"`
+	Checksum    string `json:"checksum,omitempty"` // hex SHA-256, set once complete
+	HashState   []byte `json:"-"`                  // marshalled SHA-256 state of the first Offset bytes
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	ExpiresAt time.Time `gorm:"index" json:"expiresAt"`
+}
+
+// Complete reports whether every byte of the upload has been received.
+func (u *Upload) Complete() bool {
+	return u.Offset >= u.Length
+}
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 
 	"github.com/bwmarrin/discordgo"
@@ -23,6 +25,10 @@ type User struct {
 	PublicFlags   int    `json:"publicFlags"` // discordgo.UserFlags is an int
 
 	IsAdmin bool `json:"isAdmin"`
+
+	// RolesChangedAt is when the user last lost a role or left the guild.
+	// Sessions issued before it carry a stale role snapshot.
+	RolesChangedAt *time.Time `json:"rolesChangedAt"`
 }
 
 func (u *User) ToDiscord() *discordgo.User {
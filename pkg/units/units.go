@@ -1,5 +1,11 @@
 package units
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	_        = iota             // Ignore the first value (0)
 	Kibibyte = 1 << (10 * iota) // 1 KiB = 1024 bytes
@@ -12,3 +18,31 @@ const (
 const (
 	DiscordLimit = 8 * Mebibyte // 8 MiB
 )
+
+var sizeSuffixes = []struct {
+	suffix string
+	scale  int64
+}{
+	{"kib", Kibibyte}, {"mib", Mebibyte}, {"gib", Gibibyte}, {"tib", Tebibyte},
+	{"kb", 1e3}, {"mb", 1e6}, {"gb", 1e9}, {"tb", 1e12},
+	{"k", Kibibyte}, {"m", Mebibyte}, {"g", Gibibyte}, {"t", Tebibyte},
+	{"b", 1},
+}
+
+// ParseSize parses a human readable byte size such as "512MiB", "2GB", "1g"
+// or a plain number of bytes.
+func ParseSize(s string) (int64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	scale := int64(1)
+	for _, u := range sizeSuffixes {
+		if rest, ok := strings.CutSuffix(s, u.suffix); ok {
+			s, scale = strings.TrimSpace(rest), u.scale
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(scale)), nil
+}